@@ -0,0 +1,196 @@
+package main
+
+import (
+	"fmt"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/arn"
+	"github.com/aws/aws-sdk-go/aws/credentials/stscreds"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/cloudtrail"
+	"github.com/aws/aws-sdk-go/service/ec2"
+	"github.com/aws/aws-sdk-go/service/elb"
+	"github.com/aws/aws-sdk-go/service/elbv2"
+	"github.com/aws/aws-sdk-go/service/organizations"
+	"github.com/aws/aws-sdk-go/service/resourcegroupstaggingapi"
+	"github.com/aws/aws-sdk-go/service/s3"
+	"strings"
+	"sync"
+)
+
+// accountTarget is an account to audit, through a role assumed from the
+// credentials of the environment.
+type accountTarget struct {
+	Account string
+	RoleArn string
+}
+
+// baseSession is the session of the credentials of the environment, the
+// roles of the accounts are assumed from it.
+var baseSession *session.Session
+
+// currentAccount is the account being audited, "" for the account of the
+// credentials of the environment.
+var currentAccount string
+
+// currentRoleArn is the role currentAccount is audited through, "" for the
+// credentials of the environment.
+var currentRoleArn string
+
+// searchErrors are the errors that left the search of the current account
+// incomplete, ex: an AccessDenied, reset by useAccount. The account is then
+// reported with an error and nothing is deleted.
+var searchErrors = []string{}
+var searchErrorsMutex sync.Mutex
+
+// searchFailed records that the search of the current account is incomplete.
+func searchFailed(what string, err error) {
+	searchErrorsMutex.Lock()
+	defer searchErrorsMutex.Unlock()
+	searchErrors = append(searchErrors, what+": "+err.Error())
+}
+
+// searchError returns the errors of the search of the current account, ""
+// when it is complete.
+func searchError() string {
+	searchErrorsMutex.Lock()
+	defer searchErrorsMutex.Unlock()
+	return strings.Join(searchErrors, "; ")
+}
+
+// accountTargets returns the accounts to audit, from -role-arn, -accounts and
+// -all-accounts. It returns a single target with no role when none is set.
+// The account of the credentials of the environment, ex: the management
+// account, has no role to assume and is left out of -all-accounts.
+func accountTargets() ([]*accountTarget, error) {
+	targets := []*accountTarget{}
+
+	if roleArn != "" {
+		parsed, err := arn.Parse(roleArn)
+		if err != nil {
+			return nil, err
+		}
+		targets = append(targets, &accountTarget{
+			Account: parsed.AccountID,
+			RoleArn: roleArn,
+		})
+	}
+
+	accounts := []string{}
+	for _, account := range strings.Split(accountsString, ",") {
+		if account = strings.TrimSpace(account); account != "" {
+			accounts = append(accounts, account)
+		}
+	}
+	if allAccounts {
+		organizationAccounts, err := listOrganizationAccounts()
+		if err != nil {
+			return nil, err
+		}
+		own, err := callerAccount()
+		if err != nil {
+			return nil, err
+		}
+		for _, account := range organizationAccounts {
+			if account == own {
+				logOut.Println("Skipping account", own, "of the credentials, audit it without -all-accounts")
+				continue
+			}
+			accounts = append(accounts, account)
+		}
+	}
+	for _, account := range accounts {
+		targets = append(targets, &accountTarget{
+			Account: account,
+			RoleArn: "arn:aws:iam::" + account + ":role/" + roleName,
+		})
+	}
+
+	if len(targets) == 0 {
+		targets = append(targets, &accountTarget{})
+	}
+	return targets, nil
+}
+
+// listOrganizationAccounts returns the active accounts of the organization of
+// the management account.
+func listOrganizationAccounts() ([]string, error) {
+	svc := organizations.New(baseSession)
+	accounts := []string{}
+
+	err := svc.ListAccountsPages(&organizations.ListAccountsInput{},
+		func(page *organizations.ListAccountsOutput, lastPage bool) bool {
+			for _, account := range page.Accounts {
+				if aws.StringValue(account.Status) == organizations.AccountStatusActive {
+					accounts = append(accounts, aws.StringValue(account.Id))
+				}
+			}
+			return true
+		})
+	return accounts, err
+}
+
+// useAccount makes the clients call the account of target, with the
+// credentials of its role. The caches of the previous account are dropped.
+// It returns an error when the role cannot be assumed, or is not in the
+// account.
+func useAccount(target *accountTarget) error {
+	v("Using account", target.Account, target.RoleArn)
+	currentAccount = target.Account
+	currentRoleArn = target.RoleArn
+	resetClients(target)
+
+	searchErrorsMutex.Lock()
+	searchErrors = []string{}
+	searchErrorsMutex.Unlock()
+
+	if target.RoleArn == "" {
+		return nil
+	}
+	account, err := callerAccount()
+	if err != nil {
+		return fmt.Errorf("cannot assume %s: %s", target.RoleArn, err)
+	}
+	if account != target.Account {
+		return fmt.Errorf("role %s is in account %s", target.RoleArn, account)
+	}
+	return nil
+}
+
+// resetClients drops the clients and the caches, the clients are made again
+// from the session of target.
+func resetClients(target *accountTarget) {
+	clientsMutex.Lock()
+	defer clientsMutex.Unlock()
+	sessionsMutex.Lock()
+	defer sessionsMutex.Unlock()
+
+	if target.RoleArn == "" {
+		sess = baseSession
+	} else {
+		sess = baseSession.Copy(&aws.Config{
+			Credentials: stscreds.NewCredentials(baseSession, target.RoleArn, func(p *stscreds.AssumeRoleProvider) {
+				p.RoleSessionName = "janitor"
+			}),
+		})
+	}
+
+	sessions = map[string]*session.Session{}
+	svcCloudtrail = map[string]*cloudtrail.CloudTrail{}
+	svcEc2 = map[string]*ec2.EC2{}
+	svcElb = map[string]*elb.ELB{}
+	svcElbV2 = map[string]*elbv2.ELBV2{}
+	svcIam = nil
+	svcRoute53 = nil
+	svcS3 = map[string]*s3.S3{}
+	svcSts = nil
+	svcTagging = map[string]*resourcegroupstaggingapi.ResourceGroupsTaggingAPI{}
+	accountId = ""
+
+	defaultVpcsMutex.Lock()
+	defaultVpcs = map[string]map[string]bool{}
+	defaultVpcsMutex.Unlock()
+
+	iamPolicyArnsMutex.Lock()
+	iamPolicyArns = nil
+	iamPolicyArnsMutex.Unlock()
+}
@@ -0,0 +1,69 @@
+package main
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestAccountTargets(t *testing.T) {
+	defer func(arn, name, accounts string) {
+		roleArn, roleName, accountsString = arn, name, accounts
+	}(roleArn, roleName, accountsString)
+
+	roleArn = ""
+	accountsString = ""
+	targets, err := accountTargets()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(targets) != 1 || *targets[0] != (accountTarget{}) {
+		t.Errorf("no account flags: got %+v, want the account of the environment", targets)
+	}
+
+	roleArn = "arn:aws:iam::123456789012:role/Audit"
+	roleName = "OrganizationAccountAccessRole"
+	accountsString = " 210987654321,,333333333333 "
+	targets, err = accountTargets()
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []accountTarget{
+		{Account: "123456789012", RoleArn: "arn:aws:iam::123456789012:role/Audit"},
+		{Account: "210987654321", RoleArn: "arn:aws:iam::210987654321:role/OrganizationAccountAccessRole"},
+		{Account: "333333333333", RoleArn: "arn:aws:iam::333333333333:role/OrganizationAccountAccessRole"},
+	}
+	if len(targets) != len(want) {
+		t.Fatalf("got %d targets, want %d", len(targets), len(want))
+	}
+	for i := range want {
+		if *targets[i] != want[i] {
+			t.Errorf("target %d: got %+v, want %+v", i, *targets[i], want[i])
+		}
+	}
+
+	roleArn = "not-an-arn"
+	if _, err := accountTargets(); err == nil {
+		t.Error("a bad -role-arn is accepted")
+	}
+}
+
+func TestSearchErrors(t *testing.T) {
+	if message := searchError(); message != "" {
+		t.Fatalf("search failed before it started: %s", message)
+	}
+
+	searchFailed("LookupEvents of alice in us-east-1", errors.New("AccessDenied"))
+	searchFailed("GetResources in eu-west-1", errors.New("AccessDenied"))
+	want := "LookupEvents of alice in us-east-1: AccessDenied; GetResources in eu-west-1: AccessDenied"
+	if message := searchError(); message != want {
+		t.Errorf("got %q, want %q", message, want)
+	}
+
+	// The next account starts with a clean slate
+	if err := useAccount(&accountTarget{}); err != nil {
+		t.Fatal(err)
+	}
+	if message := searchError(); message != "" {
+		t.Errorf("errors of the previous account kept: %s", message)
+	}
+}
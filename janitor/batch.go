@@ -0,0 +1,137 @@
+package main
+
+import (
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/service/ec2"
+	"strings"
+)
+
+// Max number of values in an EC2 Describe* filter
+var ec2BatchSize int = 200
+
+// Max number of names or ARNs in an ELB Describe* call
+var elbBatchSize int = 20
+
+// chunks splits names into slices of at most size names.
+func chunks(names []string, size int) [][]string {
+	result := [][]string{}
+	for size < len(names) {
+		result = append(result, names[:size])
+		names = names[size:]
+	}
+	if len(names) > 0 {
+		result = append(result, names)
+	}
+	return result
+}
+
+func ec2Filter(name string, values []string) []*ec2.Filter {
+	return []*ec2.Filter{
+		{
+			Name:   aws.String(name),
+			Values: aws.StringSlice(values),
+		},
+	}
+}
+
+// isBatchNotFound returns true if a batched call failed because at least one
+// of the names or ids does not exist. Only the explicit NotFound codes of the
+// services mean that.
+func isBatchNotFound(err error) bool {
+	if aerr, ok := err.(awserr.Error); ok {
+		return strings.HasSuffix(aerr.Code(), "NotFound")
+	}
+	return false
+}
+
+// isBatchMalformed returns true if a batched call failed because at least
+// one of the names or ids is malformed: the name is isolated like a missing
+// one, but cannot be said to be gone.
+func isBatchMalformed(err error) bool {
+	if aerr, ok := err.(awserr.Error); ok {
+		return strings.HasSuffix(aerr.Code(), "Malformed") ||
+			aerr.Code() == "ValidationError"
+	}
+	return false
+}
+
+// batchErrors returns err for each name, when a whole batch could not be
+// verified.
+func batchErrors(names []string, err error) map[string]error {
+	errs := map[string]error{}
+	for _, name := range names {
+		errs[name] = err
+	}
+	return errs
+}
+
+// existsBisect checks the existence of names with a single call.
+// Some APIs fail the whole call when one of the names does not exist,
+// in that case names are split in two halves which are checked separately,
+// until the missing names are isolated. The malformed names are isolated
+// the same way, and get the error.
+func existsBisect(names []string, call func(names []string) (map[string]bool, error)) (map[string]bool, map[string]error) {
+	found, err := call(names)
+	if err == nil {
+		return found, nil
+	}
+
+	if !isBatchNotFound(err) && !isBatchMalformed(err) {
+		return nil, batchErrors(names, err)
+	}
+	if len(names) == 1 {
+		if isBatchNotFound(err) {
+			return map[string]bool{}, nil
+		}
+		return nil, batchErrors(names, err)
+	}
+
+	v("# bisecting", len(names), "names:", err.Error())
+	half := len(names) / 2
+	found, errs := existsBisect(names[:half], call)
+	if found == nil {
+		found = map[string]bool{}
+	}
+	if errs == nil {
+		errs = map[string]error{}
+	}
+	moreFound, moreErrs := existsBisect(names[half:], call)
+	for name, exists := range moreFound {
+		found[name] = exists
+	}
+	for name, err := range moreErrs {
+		errs[name] = err
+	}
+	return found, errs
+}
+
+// isArn returns true if name is an ARN.
+func isArn(name string) bool {
+	return strings.HasPrefix(name, "arn:")
+}
+
+// existsSupported checks the existence of the names an API can look up with
+// exists. The others, ex: ELBv2 names that are not ARNs, get an error, so
+// they are reported as unverified instead of gone.
+func existsSupported(names []string, supported func(name string) bool, reason string,
+	exists func(names []string) (map[string]bool, map[string]error)) (map[string]bool, map[string]error) {
+	lookups := []string{}
+	errs := map[string]error{}
+	for _, name := range names {
+		if supported(name) {
+			lookups = append(lookups, name)
+			continue
+		}
+		errs[name] = awserr.New("UnsupportedName", name+" is "+reason+", its existence cannot be verified", nil)
+	}
+	if len(lookups) == 0 {
+		return map[string]bool{}, errs
+	}
+
+	found, lookupErrs := exists(lookups)
+	for name, err := range lookupErrs {
+		errs[name] = err
+	}
+	return found, errs
+}
@@ -0,0 +1,142 @@
+package main
+
+import (
+	"errors"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"reflect"
+	"sort"
+	"testing"
+)
+
+// describeExisting returns a batch call failing with code when one of the
+// names is not in existing, like DescribeInstances does with
+// InvalidInstanceID.NotFound.
+func describeExisting(existing []string, code string, calls *int) func(names []string) (map[string]bool, error) {
+	return func(names []string) (map[string]bool, error) {
+		*calls++
+		exists := map[string]bool{}
+		for _, name := range existing {
+			exists[name] = true
+		}
+		found := map[string]bool{}
+		for _, name := range names {
+			if !exists[name] {
+				return nil, awserr.New(code, name+" does not exist", nil)
+			}
+			found[name] = true
+		}
+		return found, nil
+	}
+}
+
+func TestExistsBisect(t *testing.T) {
+	tests := []struct {
+		name      string
+		names     []string
+		existing  []string
+		code      string
+		wantFound []string
+		wantErrs  []string
+		wantCalls int
+	}{
+		{
+			name:      "all exist",
+			names:     []string{"a", "b", "c", "d"},
+			existing:  []string{"a", "b", "c", "d"},
+			code:      "InvalidInstanceID.NotFound",
+			wantFound: []string{"a", "b", "c", "d"},
+			wantCalls: 1,
+		},
+		{
+			name:      "one missing",
+			names:     []string{"a", "b", "c", "d"},
+			existing:  []string{"a", "b", "d"},
+			code:      "InvalidInstanceID.NotFound",
+			wantFound: []string{"a", "b", "d"},
+			// abcd, ab, cd, c, d
+			wantCalls: 5,
+		},
+		{
+			name:      "none exist",
+			names:     []string{"a", "b", "c"},
+			existing:  []string{},
+			code:      "InvalidGroup.NotFound",
+			wantFound: []string{},
+			// abc, a, bc, b, c
+			wantCalls: 5,
+		},
+		{
+			name:      "malformed ids are isolated, not missing",
+			names:     []string{"a", "b", "c"},
+			existing:  []string{"b", "c"},
+			code:      "InvalidVpcID.Malformed",
+			wantFound: []string{"b", "c"},
+			wantErrs:  []string{"a"},
+			// abc, a, bc
+			wantCalls: 3,
+		},
+		{
+			name:      "validation errors are not missing",
+			names:     []string{"a"},
+			existing:  []string{},
+			code:      "ValidationError",
+			wantFound: []string{},
+			wantErrs:  []string{"a"},
+			wantCalls: 1,
+		},
+		{
+			name:      "other errors are not bisected",
+			names:     []string{"a", "b", "c"},
+			existing:  []string{"a"},
+			code:      "UnauthorizedOperation",
+			wantFound: []string{},
+			wantErrs:  []string{"a", "b", "c"},
+			wantCalls: 1,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			calls := 0
+			found, errs := existsBisect(test.names, describeExisting(test.existing, test.code, &calls))
+
+			gotFound := []string{}
+			for name, exists := range found {
+				if exists {
+					gotFound = append(gotFound, name)
+				}
+			}
+			sort.Strings(gotFound)
+			if !reflect.DeepEqual(gotFound, test.wantFound) {
+				t.Errorf("found %v, want %v", gotFound, test.wantFound)
+			}
+
+			gotErrs := []string{}
+			for name := range errs {
+				gotErrs = append(gotErrs, name)
+			}
+			sort.Strings(gotErrs)
+			if len(gotErrs) > 0 || len(test.wantErrs) > 0 {
+				if !reflect.DeepEqual(gotErrs, test.wantErrs) {
+					t.Errorf("errors for %v, want %v", gotErrs, test.wantErrs)
+				}
+			}
+
+			if calls != test.wantCalls {
+				t.Errorf("%d calls, want %d", calls, test.wantCalls)
+			}
+		})
+	}
+}
+
+func TestExistsBisectError(t *testing.T) {
+	err := errors.New("connection reset")
+	_, errs := existsBisect([]string{"a", "b"}, func(names []string) (map[string]bool, error) {
+		return nil, err
+	})
+	for _, name := range []string{"a", "b"} {
+		if errs[name] != err {
+			t.Errorf("error of %s = %v, want %v", name, errs[name], err)
+		}
+	}
+}
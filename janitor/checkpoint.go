@@ -0,0 +1,135 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"sync"
+	"time"
+)
+
+// checkpoint is the progress of the CloudTrail scans, saved to the
+// -checkpoint file so that an interrupted run can continue with -resume.
+type checkpoint struct {
+	User      string                `json:"user"`
+	StartTime time.Time             `json:"start_time"`
+	Scans     map[string]*scanState `json:"scans"`
+}
+
+// scanState is the progress of the scan of the events of one principal in
+// one region.
+type scanState struct {
+	NextToken string      `json:"next_token,omitempty"`
+	Done      bool        `json:"done"`
+	Resources []*Resource `json:"resources"`
+}
+
+// Save the checkpoint every checkpointPages pages of events
+var checkpointPages int = 10
+
+var checkpointState *checkpoint
+var checkpointMutex sync.Mutex
+
+func newCheckpoint(user string, start time.Time) *checkpoint {
+	return &checkpoint{
+		User:      user,
+		StartTime: start,
+		Scans:     map[string]*scanState{},
+	}
+}
+
+// loadCheckpoint reads a checkpoint file. It fails if the checkpoint was
+// saved by a run for another user or start time.
+func loadCheckpoint(path string, user string, start time.Time) (*checkpoint, error) {
+	content, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	c := &checkpoint{}
+	if err := json.Unmarshal(content, c); err != nil {
+		return nil, err
+	}
+	if c.User != user || !c.StartTime.Equal(start) {
+		return nil, fmt.Errorf("checkpoint is for user %s starting at %s", c.User, c.StartTime)
+	}
+	if c.Scans == nil {
+		c.Scans = map[string]*scanState{}
+	}
+	return c, nil
+}
+
+// checkpointScan returns the state of the scan of principal in region after
+// start, nil when checkpoints are disabled.
+func checkpointScan(region string, principal string, start time.Time) *scanState {
+	if checkpointState == nil {
+		return nil
+	}
+
+	checkpointMutex.Lock()
+	defer checkpointMutex.Unlock()
+
+	key := region + " " + principal + " " + start.Format(time.RFC3339)
+	if currentAccount != "" {
+		key = currentAccount + " " + key
+	}
+	if checkpointState.Scans[key] == nil {
+		checkpointState.Scans[key] = &scanState{Resources: []*Resource{}}
+	}
+	return checkpointState.Scans[key]
+}
+
+// update records the progress of a scan. Scans of several users run
+// concurrently, the resources are copied so that saving does not race with
+// the scan updating them.
+func (state *scanState) update(nextToken string, resources []*Resource, done bool) {
+	copies := copyResources(resources)
+
+	checkpointMutex.Lock()
+	defer checkpointMutex.Unlock()
+
+	state.NextToken = nextToken
+	state.Resources = copies
+	state.Done = done
+}
+
+// resources returns a copy of the resources found so far.
+func (state *scanState) resources() []*Resource {
+	checkpointMutex.Lock()
+	defer checkpointMutex.Unlock()
+
+	return copyResources(state.Resources)
+}
+
+func copyResources(resources []*Resource) []*Resource {
+	copies := make([]*Resource, len(resources))
+	for i, resource := range resources {
+		c := *resource
+		copies[i] = &c
+	}
+	return copies
+}
+
+// saveCheckpoint writes the checkpoint to the -checkpoint file. The file is
+// replaced atomically, so a run killed while saving keeps the previous one.
+func saveCheckpoint() {
+	if checkpointState == nil {
+		return
+	}
+
+	checkpointMutex.Lock()
+	defer checkpointMutex.Unlock()
+
+	content, err := json.Marshal(checkpointState)
+	if err == nil {
+		err = ioutil.WriteFile(checkpointFile+".tmp", content, 0600)
+	}
+	if err == nil {
+		err = os.Rename(checkpointFile+".tmp", checkpointFile)
+	}
+	if err != nil {
+		logErr.Println("Got error saving checkpoint", checkpointFile)
+		logErr.Println(err.Error())
+	}
+}
@@ -0,0 +1,67 @@
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestCheckpointRoundTrip(t *testing.T) {
+	dir, err := ioutil.TempDir("", "janitor")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	defer func(file string, state *checkpoint, account string) {
+		checkpointFile, checkpointState, currentAccount = file, state, account
+	}(checkpointFile, checkpointState, currentAccount)
+
+	start := time.Date(2019, 1, 14, 7, 4, 25, 0, time.UTC)
+	checkpointFile = filepath.Join(dir, "janitor.checkpoint")
+	checkpointState = newCheckpoint("alice", start)
+	currentAccount = "123456789012"
+
+	found := []*Resource{{Type: "AWS::EC2::Instance", Name: "i-0123", Region: "us-east-1", EventName: "RunInstances", Principal: "alice"}}
+	checkpointScan("us-east-1", "alice", start).update("token-2", found, false)
+	checkpointScan("eu-west-1", "alice", start).update("", []*Resource{}, true)
+	// The scan keeps its resources, the checkpoint has a copy
+	found[0].Name = "i-changed"
+	saveCheckpoint()
+
+	if _, err := os.Stat(checkpointFile + ".tmp"); !os.IsNotExist(err) {
+		t.Errorf("temporary file left: %v", err)
+	}
+	if _, err := loadCheckpoint(checkpointFile, "bob", start); err == nil {
+		t.Error("checkpoint of alice loaded for bob")
+	}
+	if _, err := loadCheckpoint(checkpointFile, "alice", start.Add(time.Hour)); err == nil {
+		t.Error("checkpoint loaded for another start time")
+	}
+
+	loaded, err := loadCheckpoint(checkpointFile, "alice", start)
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkpointState = loaded
+
+	state := checkpointScan("us-east-1", "alice", start)
+	if state.Done || state.NextToken != "token-2" {
+		t.Errorf("us-east-1 resumes from %q, done %v", state.NextToken, state.Done)
+	}
+	resources := state.resources()
+	if len(resources) != 1 || resources[0].Name != "i-0123" || resources[0].EventName != "RunInstances" {
+		t.Errorf("us-east-1 resources %+v", resources)
+	}
+	if state := checkpointScan("eu-west-1", "alice", start); !state.Done {
+		t.Error("eu-west-1 scan not done after resume")
+	}
+
+	// Another account has scans of its own
+	currentAccount = "210987654321"
+	if state := checkpointScan("us-east-1", "alice", start); state.Done || state.NextToken != "" || len(state.Resources) != 0 {
+		t.Errorf("scan of another account resumed: %+v", state)
+	}
+}
@@ -0,0 +1,168 @@
+package main
+
+import (
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/route53"
+	"sort"
+	"strings"
+)
+
+// Kubernetes and OpenShift tag the resources of a cluster with
+// kubernetes.io/cluster/<infra id>, owned for the resources the cluster
+// created, ex: the volumes of the gp2 storage class, the ELBs of the
+// services, shared for the others.
+const clusterTagPrefix = "kubernetes.io/cluster/"
+
+// backendCluster is reported in Resource.FoundBy for the resources found
+// by their cluster tag.
+const backendCluster = "cluster"
+
+// Route53 tagged resources are only returned in this region
+const route53TaggingRegion = "us-east-1"
+
+// clusterInfraIDs returns the infra ids of the clusters owning resources,
+// from their cluster tags, with the first resource revealing each id. The
+// clusters a resource is only shared with, ex: a VPC installed into, are not
+// returned.
+func clusterInfraIDs(resources []*Resource) ([]string, map[string]*Resource) {
+	ids := []string{}
+	revealedBy := map[string]*Resource{}
+
+	for _, resource := range resources {
+		if resource.Details == nil {
+			continue
+		}
+		for key, value := range resource.Details.Tags {
+			if !strings.HasPrefix(key, clusterTagPrefix) || value != "owned" {
+				continue
+			}
+			id := strings.TrimPrefix(key, clusterTagPrefix)
+			if _, ok := revealedBy[id]; !ok && id != "" {
+				ids = append(ids, id)
+				revealedBy[id] = resource
+			}
+		}
+	}
+
+	sort.Strings(ids)
+	return ids, revealedBy
+}
+
+// searchClusters returns the resources owned by the clusters of resources:
+// the resources tagged kubernetes.io/cluster/<infra id>=owned, and the
+// records of the cluster domain in the public zones, ex: api and *.apps of
+// OpenShift. The domain of a cluster is the name of its private zone.
+// The resources must be described first, and be those that may be deleted:
+// a protected resource does not make its cluster deleted.
+func searchClusters(regions []string, resources []*Resource) []*Resource {
+	ids, revealedBy := clusterInfraIDs(resources)
+	found := []*Resource{}
+
+	searched := append([]string{}, regions...)
+	if !contains(searched, route53TaggingRegion) {
+		searched = append(searched, route53TaggingRegion)
+	}
+
+	for _, id := range ids {
+		v("Searching resources of cluster", id)
+		principal := "cluster/" + id
+		chain := append(append([]string{}, revealedBy[id].Chain...), principal)
+		clusterResources := []*Resource{}
+
+		for _, region := range searched {
+			for _, resource := range searchTaggedResources(region, []string{clusterTagPrefix + id + "=owned"}) {
+				if !contains(regions, region) && resource.Region != globalRegion {
+					continue
+				}
+				clusterResources = mergeResources(clusterResources, []*Resource{resource})
+			}
+		}
+
+		for _, resource := range clusterResources {
+			if resource.Type == "AWS::Route53::HostedZone" {
+				clusterResources = mergeResources(clusterResources, clusterRecordSets(resource.Name))
+			}
+		}
+
+		for _, resource := range clusterResources {
+			resource.Principal = principal
+			resource.Chain = chain
+			resource.FoundBy = []string{backendCluster}
+			resource.startTime = revealedBy[id].startTime
+		}
+		found = mergeResources(found, clusterResources)
+	}
+
+	return found
+}
+
+// clusterRecordSets returns the record sets of the domain of the private
+// zone of a cluster in the public zones of its parent domains.
+func clusterRecordSets(privateZoneId string) []*Resource {
+	svc := route53Client()
+	resources := []*Resource{}
+
+	zone, err := svc.GetHostedZone(&route53.GetHostedZoneInput{
+		Id: aws.String(route53HostedZoneId(privateZoneId)),
+	})
+	if err != nil {
+		logErr.Println("Got error calling GetHostedZone for", privateZoneId)
+		logErr.Println(err.Error())
+		return resources
+	}
+	domain := aws.StringValue(zone.HostedZone.Name)
+
+	publicZones := []*route53.HostedZone{}
+	err = svc.ListHostedZonesPages(&route53.ListHostedZonesInput{},
+		func(page *route53.ListHostedZonesOutput, lastPage bool) bool {
+			for _, hostedZone := range page.HostedZones {
+				private := hostedZone.Config != nil && aws.BoolValue(hostedZone.Config.PrivateZone)
+				if !private && strings.HasSuffix(domain, "."+aws.StringValue(hostedZone.Name)) {
+					publicZones = append(publicZones, hostedZone)
+				}
+			}
+			return true
+		})
+	if err != nil {
+		logErr.Println("Got error calling ListHostedZones:")
+		logErr.Println(err.Error())
+		return resources
+	}
+
+	for _, publicZone := range publicZones {
+		zoneId := route53HostedZoneId(aws.StringValue(publicZone.Id))
+		seen := map[string]bool{}
+		err = svc.ListResourceRecordSetsPages(
+			&route53.ListResourceRecordSetsInput{
+				HostedZoneId: aws.String(zoneId),
+			},
+			func(page *route53.ListResourceRecordSetsOutput, lastPage bool) bool {
+				for _, set := range page.ResourceRecordSets {
+					name := aws.StringValue(set.Name)
+					if name != domain && !strings.HasSuffix(name, "."+domain) {
+						continue
+					}
+					recordSet := route53RecordSetName(zoneId, aws.StringValue(set.Type), name)
+					if seen[recordSet] {
+						continue
+					}
+					seen[recordSet] = true
+					resources = append(resources, &Resource{
+						Type:       "AWS::Route53::RecordSet",
+						Name:       recordSet,
+						Region:     globalRegion,
+						Parent:     privateZoneId,
+						ParentType: "AWS::Route53::HostedZone",
+					})
+					v("└──", globalRegion, "AWS::Route53::RecordSet", recordSet)
+				}
+				return true
+			})
+		if err != nil {
+			logErr.Println("Got error calling ListResourceRecordSets for", zoneId)
+			logErr.Println(err.Error())
+		}
+	}
+
+	return resources
+}
@@ -0,0 +1,46 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestClusterInfraIDs(t *testing.T) {
+	tagged := func(name string, tags map[string]string) *Resource {
+		return &Resource{Type: "AWS::EC2::Instance", Name: name, Region: "us-east-1", Details: &ResourceDetails{Tags: tags}}
+	}
+	worker := tagged("i-worker", map[string]string{
+		"Name":                             "ci-op-abcd-worker",
+		"kubernetes.io/cluster/ci-op-abcd": "owned",
+	})
+	master := tagged("i-master", map[string]string{
+		"kubernetes.io/cluster/ci-op-abcd": "owned",
+		"kubernetes.io/cluster/ci-op-efgh": "owned",
+	})
+	vpc := tagged("vpc-0123", map[string]string{
+		"kubernetes.io/cluster/ci-op-shared": "shared",
+	})
+	empty := tagged("i-empty", map[string]string{
+		"kubernetes.io/cluster/": "owned",
+	})
+	undescribed := &Resource{Type: "AWS::EC2::Instance", Name: "i-undescribed", Region: "us-east-1"}
+
+	ids, revealedBy := clusterInfraIDs([]*Resource{undescribed, vpc, worker, empty, master})
+
+	if want := []string{"ci-op-abcd", "ci-op-efgh"}; !reflect.DeepEqual(ids, want) {
+		t.Errorf("ids %q, want %q: shared and empty ids are not clusters of the user", ids, want)
+	}
+	if revealedBy["ci-op-abcd"] != worker {
+		t.Errorf("ci-op-abcd revealed by %v, want the first resource tagged with it", revealedBy["ci-op-abcd"])
+	}
+	if revealedBy["ci-op-efgh"] != master {
+		t.Errorf("ci-op-efgh revealed by %v", revealedBy["ci-op-efgh"])
+	}
+	if _, ok := revealedBy["ci-op-shared"]; ok {
+		t.Error("a cluster the VPC is shared with is revealed")
+	}
+
+	if ids, _ := clusterInfraIDs(nil); len(ids) != 0 {
+		t.Errorf("ids of no resources %q", ids)
+	}
+}
@@ -0,0 +1,197 @@
+package main
+
+import (
+	"gopkg.in/yaml.v2"
+	"io/ioutil"
+)
+
+// Hours in a month, as used by the AWS pricing pages
+const hoursPerMonth = 730
+
+// Cost is the estimated on-demand cost of a resource, in USD.
+type Cost struct {
+	Hourly  float64 `json:"hourly" yaml:"hourly"`
+	Monthly float64 `json:"monthly" yaml:"monthly"`
+}
+
+// priceTable holds the prices used to estimate costs, in USD. The bundled
+// table is the us-east-1 on-demand price list, -prices overrides its
+// entries.
+type priceTable struct {
+	// Instances is the hourly price of each instance type, ex: m5.xlarge
+	Instances map[string]float64 `yaml:"instances"`
+	// Volumes is the price of a GB-month of each volume type, ex: gp2
+	Volumes map[string]float64 `yaml:"volumes"`
+	// Snapshots is the price of a GB-month of snapshot
+	Snapshots float64 `yaml:"snapshots"`
+	// Resources is the hourly price of the resource types billed per
+	// resource, ex: AWS::EC2::NatGateway
+	Resources map[string]float64 `yaml:"resources"`
+}
+
+var defaultPrices = &priceTable{
+	Instances: map[string]float64{
+		"t2.nano":     0.0058,
+		"t2.micro":    0.0116,
+		"t2.small":    0.023,
+		"t2.medium":   0.0464,
+		"t2.large":    0.0928,
+		"t2.xlarge":   0.1856,
+		"t2.2xlarge":  0.3712,
+		"t3.nano":     0.0052,
+		"t3.micro":    0.0104,
+		"t3.small":    0.0208,
+		"t3.medium":   0.0416,
+		"t3.large":    0.0832,
+		"t3.xlarge":   0.1664,
+		"t3.2xlarge":  0.3328,
+		"m4.large":    0.1,
+		"m4.xlarge":   0.2,
+		"m4.2xlarge":  0.4,
+		"m4.4xlarge":  0.8,
+		"m5.large":    0.096,
+		"m5.xlarge":   0.192,
+		"m5.2xlarge":  0.384,
+		"m5.4xlarge":  0.768,
+		"m5.8xlarge":  1.536,
+		"m6i.large":   0.096,
+		"m6i.xlarge":  0.192,
+		"m6i.2xlarge": 0.384,
+		"m6i.4xlarge": 0.768,
+		"m6i.8xlarge": 1.536,
+		"c5.large":    0.085,
+		"c5.xlarge":   0.17,
+		"c5.2xlarge":  0.34,
+		"c5.4xlarge":  0.68,
+		"r5.large":    0.126,
+		"r5.xlarge":   0.252,
+		"r5.2xlarge":  0.504,
+		"r5.4xlarge":  1.008,
+	},
+	Volumes: map[string]float64{
+		"gp2":      0.1,
+		"gp3":      0.08,
+		"io1":      0.125,
+		"io2":      0.125,
+		"st1":      0.045,
+		"sc1":      0.015,
+		"standard": 0.05,
+	},
+	Snapshots: 0.05,
+	Resources: map[string]float64{
+		"AWS::EC2::EIP":                             0.005,
+		"AWS::EC2::NatGateway":                      0.045,
+		"AWS::ElasticLoadBalancing::LoadBalancer":   0.025,
+		"AWS::ElasticLoadBalancingV2::LoadBalancer": 0.0225,
+	},
+}
+
+var prices = defaultPrices
+
+// loadPrices reads a price table file. Its entries are merged into the
+// bundled table.
+func loadPrices(path string) (*priceTable, error) {
+	content, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	table := &priceTable{
+		Instances: copyPrices(defaultPrices.Instances),
+		Volumes:   copyPrices(defaultPrices.Volumes),
+		Snapshots: defaultPrices.Snapshots,
+		Resources: copyPrices(defaultPrices.Resources),
+	}
+	if err := yaml.UnmarshalStrict(content, table); err != nil {
+		return nil, err
+	}
+	return table, nil
+}
+
+func copyPrices(values map[string]float64) map[string]float64 {
+	c := map[string]float64{}
+	for key, value := range values {
+		c[key] = value
+	}
+	return c
+}
+
+// estimateCost returns the cost of a resource from its details. It returns
+// nil for free resources, ex: security groups, and false when the resource
+// has a cost that could not be estimated, ex: an instance type missing from
+// the table.
+func estimateCost(resource *Resource) (*Cost, bool) {
+	details := resource.Details
+
+	switch resource.Type {
+	case "AWS::EC2::Instance":
+		if details == nil || details.InstanceType == "" {
+			return nil, false
+		}
+		// Stopped instances only cost their volumes
+		if details.State == "stopped" || details.State == "stopping" {
+			return &Cost{}, true
+		}
+		hourly, ok := prices.Instances[details.InstanceType]
+		if !ok {
+			return nil, false
+		}
+		return hourlyCost(hourly), true
+
+	case "AWS::EC2::Volume":
+		if details == nil || details.VolumeType == "" {
+			return nil, false
+		}
+		price, ok := prices.Volumes[details.VolumeType]
+		if !ok {
+			return nil, false
+		}
+		return monthlyCost(price * float64(details.VolumeSize)), true
+
+	case "AWS::EC2::Snapshot":
+		// Snapshots are incremental, the volume size is an upper bound
+		if details == nil || details.VolumeSize == 0 {
+			return nil, false
+		}
+		return monthlyCost(prices.Snapshots * float64(details.VolumeSize)), true
+	}
+
+	if hourly, ok := prices.Resources[resource.Type]; ok {
+		return hourlyCost(hourly), true
+	}
+	return nil, true
+}
+
+func hourlyCost(hourly float64) *Cost {
+	return &Cost{Hourly: hourly, Monthly: hourly * hoursPerMonth}
+}
+
+func monthlyCost(monthly float64) *Cost {
+	return &Cost{Hourly: monthly / hoursPerMonth, Monthly: monthly}
+}
+
+// estimateCosts sets the Cost of the resources, and BelowMinCost for the
+// resources cheaper than minCost a month, those whose cost is unknown are
+// never cheap. Cheap resources are only hidden from the listing of the report,
+// they are deleted with the others, ex: the free subnets and security groups
+// of a costly instance. total is the cost of all the resources.
+func estimateCosts(resources []*Resource, minCost float64) (cheap int, total *Cost) {
+	total = &Cost{}
+
+	for _, resource := range resources {
+		cost, known := estimateCost(resource)
+		resource.Cost = cost
+		resource.BelowMinCost = known && minCost > 0 && (cost == nil || cost.Monthly < minCost)
+
+		if resource.BelowMinCost {
+			v("cheaper than -min-cost", resource.Type, resource.Name)
+			cheap++
+		}
+		if cost != nil {
+			total.Hourly += cost.Hourly
+			total.Monthly += cost.Monthly
+		}
+	}
+
+	return cheap, total
+}
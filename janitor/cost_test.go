@@ -0,0 +1,130 @@
+package main
+
+import (
+	"math"
+	"testing"
+)
+
+// costEqual returns true if the monthly costs are equal, to the cent.
+func costEqual(cost *Cost, monthly float64) bool {
+	return cost != nil && math.Abs(cost.Monthly-monthly) < 0.01
+}
+
+func TestEstimateCost(t *testing.T) {
+	tests := []struct {
+		name      string
+		resource  *Resource
+		want      float64
+		wantFree  bool
+		wantKnown bool
+	}{
+		{
+			name:      "running instance",
+			resource:  &Resource{Type: "AWS::EC2::Instance", Details: &ResourceDetails{InstanceType: "m5.xlarge", State: "running"}},
+			want:      0.192 * hoursPerMonth,
+			wantKnown: true,
+		},
+		{
+			name:      "stopped instance",
+			resource:  &Resource{Type: "AWS::EC2::Instance", Details: &ResourceDetails{InstanceType: "m5.xlarge", State: "stopped"}},
+			want:      0,
+			wantKnown: true,
+		},
+		{
+			name:     "unknown instance type",
+			resource: &Resource{Type: "AWS::EC2::Instance", Details: &ResourceDetails{InstanceType: "x9.huge", State: "running"}},
+			wantFree: true,
+		},
+		{
+			name:     "undescribed instance",
+			resource: &Resource{Type: "AWS::EC2::Instance"},
+			wantFree: true,
+		},
+		{
+			name:      "volume",
+			resource:  &Resource{Type: "AWS::EC2::Volume", Details: &ResourceDetails{VolumeType: "gp2", VolumeSize: 120}},
+			want:      12,
+			wantKnown: true,
+		},
+		{
+			name:      "snapshot",
+			resource:  &Resource{Type: "AWS::EC2::Snapshot", Details: &ResourceDetails{VolumeSize: 100}},
+			want:      5,
+			wantKnown: true,
+		},
+		{
+			name:      "NAT gateway",
+			resource:  &Resource{Type: "AWS::EC2::NatGateway"},
+			want:      0.045 * hoursPerMonth,
+			wantKnown: true,
+		},
+		{
+			name:      "free security group",
+			resource:  &Resource{Type: "AWS::EC2::SecurityGroup"},
+			wantFree:  true,
+			wantKnown: true,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			cost, known := estimateCost(test.resource)
+			if known != test.wantKnown {
+				t.Errorf("known = %v, want %v", known, test.wantKnown)
+			}
+			if test.wantFree {
+				if cost != nil {
+					t.Errorf("cost = %v, want none", cost)
+				}
+				return
+			}
+			if !costEqual(cost, test.want) {
+				t.Errorf("cost = %v, want %.2f a month", cost, test.want)
+			}
+		})
+	}
+}
+
+func TestEstimateCosts(t *testing.T) {
+	instance := &Resource{Type: "AWS::EC2::Instance", Details: &ResourceDetails{InstanceType: "m5.xlarge", State: "running"}}
+	volume := &Resource{Type: "AWS::EC2::Volume", Details: &ResourceDetails{VolumeType: "gp2", VolumeSize: 10}}
+	securityGroup := &Resource{Type: "AWS::EC2::SecurityGroup"}
+	vpc := &Resource{Type: "AWS::EC2::VPC"}
+	unknown := &Resource{Type: "AWS::EC2::Instance", Details: &ResourceDetails{InstanceType: "x9.huge", State: "running"}}
+	resources := []*Resource{instance, volume, securityGroup, vpc, unknown}
+
+	tests := []struct {
+		name      string
+		minCost   float64
+		wantCheap []*Resource
+	}{
+		{"no minimum", 0, []*Resource{}},
+		{"free resources", 0.5, []*Resource{securityGroup, vpc}},
+		{"cheaper than the minimum", 5, []*Resource{volume, securityGroup, vpc}},
+		{"unknown costs are never cheap", 1000, []*Resource{instance, volume, securityGroup, vpc}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			cheap, total := estimateCosts(resources, test.minCost)
+
+			if cheap != len(test.wantCheap) {
+				t.Errorf("%d cheap resources, want %d", cheap, len(test.wantCheap))
+			}
+			for _, resource := range resources {
+				want := false
+				for _, cheap := range test.wantCheap {
+					want = want || cheap == resource
+				}
+				if resource.BelowMinCost != want {
+					t.Errorf("%s BelowMinCost = %v, want %v", resource.Type, resource.BelowMinCost, want)
+				}
+			}
+
+			// The total covers all the resources, cheap ones included
+			if !costEqual(total, 0.192*hoursPerMonth+1) {
+				t.Errorf("total = %v, want %.2f a month", total, 0.192*hoursPerMonth+1)
+			}
+		})
+	}
+}
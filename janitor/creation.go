@@ -0,0 +1,55 @@
+package main
+
+import (
+	"time"
+)
+
+// isCreateEvent returns true if the event creates resources of the type.
+// Other events referencing a resource only use or modify it, ex: RunInstances
+// into a shared subnet.
+func isCreateEvent(resourceType string, eventName string) bool {
+	handler, ok := resourceHandlers[resourceType]
+	return ok && contains(handler.CreatedBy, eventName)
+}
+
+// filterPreexisting splits resources into the resources created after start,
+// or after the start time of the user that found them with -users,
+// and the resources that already existed and were only modified by the user.
+// The creation time comes from the handler Describe. When it is unknown, a
+// resource is considered created after start if its oldest event is a create
+// event of its type, or if it was found without event: by tags, as a
+// resource of a cluster of the user, or as a snapshot of an AMI, whose
+// parent went through the same filters.
+func filterPreexisting(resources []*Resource, start time.Time) (created []*Resource, preexisting []*Resource) {
+	created = []*Resource{}
+	preexisting = []*Resource{}
+
+	describeResources(resources)
+
+	for _, resource := range resources {
+		resourceStart := start
+		if !resource.startTime.IsZero() {
+			resourceStart = resource.startTime
+		}
+
+		var isCreated bool
+		if resource.Details != nil && resource.Details.CreationTime != nil {
+			isCreated = !resource.Details.CreationTime.Before(resourceStart)
+		} else if resource.EventName == "" {
+			// Found without event, by the tags or the AMI telling it belongs
+			// to the deployment
+			isCreated = true
+		} else {
+			isCreated = isCreateEvent(resource.Type, resource.EventName)
+		}
+
+		if isCreated {
+			created = append(created, resource)
+		} else {
+			v("preexisting", resource.Type, resource.Name, resource.EventName)
+			preexisting = append(preexisting, resource)
+		}
+	}
+
+	return created, preexisting
+}
@@ -0,0 +1,107 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestIsCreateEvent(t *testing.T) {
+	tests := []struct {
+		resourceType string
+		eventName    string
+		want         bool
+	}{
+		{"AWS::EC2::Instance", "RunInstances", true},
+		{"AWS::EC2::NetworkInterface", "RunInstances", true},
+		{"AWS::EC2::Subnet", "RunInstances", false},
+		{"AWS::EC2::SecurityGroup", "RunInstances", false},
+		{"AWS::EC2::VPC", "RunInstances", false},
+		{"AWS::EC2::KeyPair", "RunInstances", false},
+		{"AWS::EC2::Subnet", "CreateSubnet", true},
+		{"AWS::EC2::VPC", "CreateSubnet", false},
+		{"AWS::EC2::KeyPair", "ImportKeyPair", true},
+		{"AWS::EC2::Ami", "CopyImage", true},
+		{"AWS::EC2::Snapshot", "CreateImage", false},
+		{"AWS::EC2::EIP", "AllocateAddress", true},
+		{"AWS::EC2::EIP", "AssociateAddress", false},
+		{"AWS::Route53::HostedZone", "CreateHostedZone", true},
+		{"AWS::Route53::HostedZone", "ChangeResourceRecordSets", false},
+		{"AWS::Route53::RecordSet", "ChangeResourceRecordSets", false},
+		{"AWS::IAM::Role", "UpdateAssumeRolePolicy", false},
+		{"AWS::Unknown::Type", "CreateType", false},
+	}
+
+	for _, test := range tests {
+		if got := isCreateEvent(test.resourceType, test.eventName); got != test.want {
+			t.Errorf("isCreateEvent(%s, %s) = %v, want %v", test.resourceType, test.eventName, got, test.want)
+		}
+	}
+}
+
+func TestFilterPreexisting(t *testing.T) {
+	start := time.Date(2019, 1, 14, 7, 0, 0, 0, time.UTC)
+	before := start.Add(-time.Hour)
+	after := start.Add(time.Hour)
+
+	tests := []struct {
+		name     string
+		resource *Resource
+		want     bool
+	}{
+		{
+			name:     "created after start",
+			resource: &Resource{Type: "AWS::EC2::Instance", EventName: "StopInstances", Details: &ResourceDetails{CreationTime: &after}},
+			want:     true,
+		},
+		{
+			name:     "created at start",
+			resource: &Resource{Type: "AWS::EC2::Instance", EventName: "RunInstances", Details: &ResourceDetails{CreationTime: &start}},
+			want:     true,
+		},
+		{
+			name:     "created before start",
+			resource: &Resource{Type: "AWS::EC2::Instance", EventName: "RunInstances", Details: &ResourceDetails{CreationTime: &before}},
+			want:     false,
+		},
+		{
+			name: "created before the start of its user",
+			resource: &Resource{Type: "AWS::EC2::Volume", EventName: "CreateVolume", Details: &ResourceDetails{CreationTime: &start},
+				startTime: after},
+			want: false,
+		},
+		{
+			name:     "create event of its type",
+			resource: &Resource{Type: "AWS::EC2::Subnet", EventName: "CreateSubnet", Details: &ResourceDetails{}},
+			want:     true,
+		},
+		{
+			name:     "shared subnet of an instance",
+			resource: &Resource{Type: "AWS::EC2::Subnet", EventName: "RunInstances", Details: &ResourceDetails{}},
+			want:     false,
+		},
+		{
+			name:     "shared VPC of a subnet",
+			resource: &Resource{Type: "AWS::EC2::VPC", EventName: "CreateSubnet", Details: &ResourceDetails{}},
+			want:     false,
+		},
+		{
+			name:     "modified only",
+			resource: &Resource{Type: "AWS::EC2::SecurityGroup", EventName: "AuthorizeSecurityGroupIngress", Details: &ResourceDetails{}},
+			want:     false,
+		},
+		{
+			name:     "found by tags only",
+			resource: &Resource{Type: "AWS::EC2::VPC", Details: &ResourceDetails{}},
+			want:     true,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			created, preexisting := filterPreexisting([]*Resource{test.resource}, start)
+			if got := len(created) == 1; got != test.want || len(created)+len(preexisting) != 1 {
+				t.Errorf("created = %v, want %v", got, test.want)
+			}
+		})
+	}
+}
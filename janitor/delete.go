@@ -0,0 +1,175 @@
+package main
+
+import (
+	"errors"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"math/rand"
+	"sort"
+	"strings"
+	"time"
+)
+
+var deleteRetries int = 8
+
+var errDeleteNotSupported = errors.New("delete not supported")
+
+// deleteSet is the set of the resources being deleted, or in the deletion
+// script, by resourceKey, ex: a security group releases the rules of the
+// other groups of the set referencing it.
+var deleteSet = map[string]*Resource{}
+
+// setDeleteSet sets the resources being deleted.
+func setDeleteSet(resources []*Resource) {
+	deleteSet = map[string]*Resource{}
+	for _, resource := range resources {
+		deleteSet[resourceKey(resource.Type, resource.Region, resource.Name)] = resource
+	}
+}
+
+func resourceDelete(resource *Resource) error {
+	handler, ok := resourceHandlers[resource.Type]
+	if !ok || handler.Delete == nil {
+		return errDeleteNotSupported
+	}
+	return handler.Delete(resource)
+}
+
+// deleteLevel returns the depth of a resource type in the dependency graph
+// built from the DeleteAfter of the handlers.
+// Types of level 0 have no dependency and are deleted first.
+func deleteLevel(resourceType string, levels map[string]int) int {
+	if level, ok := levels[resourceType]; ok {
+		return level
+	}
+
+	level := 0
+	dependencies := []string{}
+	if handler, ok := resourceHandlers[resourceType]; ok {
+		dependencies = handler.DeleteAfter
+	}
+	for _, dependency := range dependencies {
+		if l := deleteLevel(dependency, levels) + 1; l > level {
+			level = l
+		}
+	}
+	levels[resourceType] = level
+	return level
+}
+
+// deleteOrder groups resources into passes. Every resource of a pass can be
+// deleted once all the previous passes are done.
+func deleteOrder(resources []*Resource) [][]*Resource {
+	levels := map[string]int{}
+	passes := map[int][]*Resource{}
+	keys := []int{}
+
+	for _, resource := range resources {
+		level := deleteLevel(resource.Type, levels)
+		if _, ok := passes[level]; !ok {
+			keys = append(keys, level)
+		}
+		passes[level] = append(passes[level], resource)
+	}
+	sort.Ints(keys)
+
+	result := [][]*Resource{}
+	for _, key := range keys {
+		result = append(result, passes[key])
+	}
+	return result
+}
+
+// isNotFound returns true if the error means the resource is already gone.
+func isNotFound(err error) bool {
+	if aerr, ok := err.(awserr.Error); ok {
+		switch aerr.Code() {
+		case "NoSuchEntity", "NoSuchBucket", "NoSuchHostedZone":
+			return true
+		}
+		return strings.HasSuffix(aerr.Code(), "NotFound")
+	}
+	return false
+}
+
+// filterKept splits resources into the resources that can be deleted and
+// the resources their handler keeps, protected by the reason to keep them.
+// Resources whose reason cannot be checked are kept.
+func filterKept(resources []*Resource) (candidates []*Resource, kept []*Resource) {
+	candidates = []*Resource{}
+	kept = []*Resource{}
+
+	for _, resource := range resources {
+		handler, ok := resourceHandlers[resource.Type]
+		if !ok || handler.Keep == nil {
+			candidates = append(candidates, resource)
+			continue
+		}
+
+		reason, err := handler.Keep(resource)
+		if err != nil {
+			logErr.Println("Got error checking", resource.Type, resource.Name)
+			logErr.Println(err.Error())
+			reason = "not checked: " + err.Error()
+		}
+		if reason == "" {
+			candidates = append(candidates, resource)
+			continue
+		}
+		v("kept", resource.Type, resource.Name, reason)
+		resource.ProtectedBy = reason
+		kept = append(kept, resource)
+	}
+	return candidates, kept
+}
+
+// deleteResources deletes resources in dependency order. Resources failing
+// because a dependency is not fully released yet are retried with an
+// exponential delay. It returns the resources that could not be deleted.
+func deleteResources(resources []*Resource) (failed []*Resource) {
+	failed = []*Resource{}
+	setDeleteSet(resources)
+
+	for i, pass := range deleteOrder(resources) {
+		v("Delete pass", i, "resources", len(pass))
+		delay := 1
+		remaining := pass
+
+		for retries := 0; len(remaining) > 0; retries++ {
+			errored := []*Resource{}
+
+			for _, resource := range remaining {
+				logOut.Println("Deleting", resource.Type, resource.Name)
+				err := resourceDelete(resource)
+				switch {
+				case err == nil, isNotFound(err):
+					logOut.Println("Deleted", resource.Type, resource.Name)
+				case err == errDeleteNotSupported:
+					logErr.Println("Type", resource.Type, "delete not supported")
+					failed = append(failed, resource)
+				case err == errMainRouteTable:
+					logOut.Println(resource.Name, "is the main route table of its VPC, not deleted")
+					failed = append(failed, resource)
+				default:
+					v(resource.Name, err.Error())
+					if retries+1 >= deleteRetries {
+						logErr.Println("Could not delete", resource.Type, resource.Name)
+						logErr.Println(err.Error())
+						failed = append(failed, resource)
+					} else {
+						errored = append(errored, resource)
+					}
+				}
+			}
+
+			remaining = errored
+			if len(remaining) > 0 {
+				randomDelay := time.Duration(delay+rand.Intn(delay)) * time.Second
+				v("# Delete pass", i, "has", len(remaining), "resources to retry... sleeping", randomDelay)
+				time.Sleep(randomDelay)
+				delay = delay * 2
+			}
+		}
+	}
+
+	return failed
+}
@@ -0,0 +1,137 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+// passNames returns the names of the resources of each pass.
+func passNames(passes [][]*Resource) [][]string {
+	result := [][]string{}
+	for _, pass := range passes {
+		names := []string{}
+		for _, resource := range pass {
+			names = append(names, resource.Name)
+		}
+		result = append(result, names)
+	}
+	return result
+}
+
+func TestDeleteOrder(t *testing.T) {
+	tests := []struct {
+		name      string
+		resources []*Resource
+		want      [][]string
+	}{
+		{
+			name:      "empty",
+			resources: []*Resource{},
+			want:      [][]string{},
+		},
+		{
+			name: "independent resources in one pass",
+			resources: []*Resource{
+				{Type: "AWS::EC2::Instance", Name: "i-1"},
+				{Type: "AWS::S3::Bucket", Name: "bucket"},
+				{Type: "AWS::EC2::KeyPair", Name: "key"},
+			},
+			want: [][]string{{"i-1", "bucket", "key"}},
+		},
+		{
+			name: "network of an instance",
+			resources: []*Resource{
+				{Type: "AWS::EC2::VPC", Name: "vpc-1"},
+				{Type: "AWS::EC2::Subnet", Name: "subnet-1"},
+				{Type: "AWS::EC2::NetworkInterface", Name: "eni-1"},
+				{Type: "AWS::EC2::Instance", Name: "i-1"},
+				{Type: "AWS::EC2::SecurityGroup", Name: "sg-1"},
+			},
+			want: [][]string{{"i-1"}, {"eni-1"}, {"subnet-1", "sg-1"}, {"vpc-1"}},
+		},
+		{
+			name: "levels without resources are skipped",
+			resources: []*Resource{
+				{Type: "AWS::EC2::VPC", Name: "vpc-1"},
+				{Type: "AWS::EC2::Instance", Name: "i-1"},
+			},
+			want: [][]string{{"i-1"}, {"vpc-1"}},
+		},
+		{
+			name: "load balancer after its listeners and target groups",
+			resources: []*Resource{
+				{Type: "AWS::ElasticLoadBalancingV2::LoadBalancer", Name: "lb"},
+				{Type: "AWS::ElasticLoadBalancingV2::TargetGroup", Name: "tg"},
+				{Type: "AWS::ElasticLoadBalancingV2::Listener", Name: "listener"},
+			},
+			want: [][]string{{"listener"}, {"tg"}, {"lb"}},
+		},
+		{
+			name: "association, route table, network ACL and DHCP options of a VPC",
+			resources: []*Resource{
+				{Type: "AWS::EC2::DHCPOptions", Name: "dopt-1"},
+				{Type: "AWS::EC2::VPC", Name: "vpc-1"},
+				{Type: "AWS::EC2::Subnet", Name: "subnet-1"},
+				{Type: "AWS::EC2::NetworkAcl", Name: "acl-1"},
+				{Type: "AWS::EC2::RouteTable", Name: "rtb-1"},
+				{Type: "AWS::EC2::SubnetRouteTableAssociation", Name: "rtbassoc-1"},
+			},
+			want: [][]string{{"rtbassoc-1"}, {"rtb-1"}, {"subnet-1"}, {"acl-1"}, {"vpc-1"}, {"dopt-1"}},
+		},
+		{
+			name: "snapshot after its AMI, policy after its role",
+			resources: []*Resource{
+				{Type: "AWS::EC2::Snapshot", Name: "snap-1"},
+				{Type: "AWS::EC2::Ami", Name: "ami-1"},
+				{Type: "AWS::IAM::Policy", Name: "policy"},
+				{Type: "AWS::IAM::Role", Name: "role"},
+			},
+			want: [][]string{{"ami-1"}, {"snap-1"}, {"role"}, {"policy"}},
+		},
+		{
+			name: "unknown types first",
+			resources: []*Resource{
+				{Type: "AWS::EC2::Subnet", Name: "subnet-1"},
+				{Type: "AWS::Unknown::Type", Name: "unknown"},
+			},
+			want: [][]string{{"unknown"}, {"subnet-1"}},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got := passNames(deleteOrder(test.resources))
+			if !reflect.DeepEqual(got, test.want) {
+				t.Errorf("deleteOrder() = %v, want %v", got, test.want)
+			}
+		})
+	}
+}
+
+// TestDeleteAfter checks that every type is deleted after the types of its
+// DeleteAfter.
+func TestDeleteAfter(t *testing.T) {
+	resources := []*Resource{}
+	for resourceType := range resourceHandlers {
+		resources = append(resources, &Resource{Type: resourceType, Name: resourceType})
+	}
+
+	pass := map[string]int{}
+	for i, resources := range deleteOrder(resources) {
+		for _, resource := range resources {
+			pass[resource.Type] = i
+		}
+	}
+
+	for resourceType, handler := range resourceHandlers {
+		for _, dependency := range handler.DeleteAfter {
+			if _, ok := resourceHandlers[dependency]; !ok {
+				t.Errorf("%s is deleted after %s, which has no handler", resourceType, dependency)
+				continue
+			}
+			if pass[dependency] >= pass[resourceType] {
+				t.Errorf("%s is deleted in pass %d, not after %s in pass %d", resourceType, pass[resourceType], dependency, pass[dependency])
+			}
+		}
+	}
+}
@@ -1,497 +1,44 @@
 package main
 
 import (
-	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/aws"
 	"github.com/aws/aws-sdk-go/service/ec2"
 )
 
-var svcEc2 *ec2.EC2
+var svcEc2 = map[string]*ec2.EC2{}
 
-func ec2InstanceExists(instanceId string) bool {
-	v("exists?", instanceId)
-	if svcEc2 == nil {
-		svcEc2 = ec2.New(sess)
-	}
-
-	input := &ec2.DescribeInstanceStatusInput{
-		InstanceIds: []*string{
-			&instanceId,
-		},
-	}
-	result, err := svcEc2.DescribeInstanceStatus(input)
-	if err != nil {
-		if aerr, ok := err.(awserr.Error); ok {
-			switch aerr.Code() {
-			case "InvalidInstanceID.NotFound":
-				return false
-			case "InvalidInstanceID.Malformed":
-				return false
-			default:
-				logErr.Println(aerr.Code())
-				logErr.Println(aerr.Error())
-			}
-		} else {
-			// Print the error, cast err to awserr.Error to get the Code and
-			// Message from an error.
-			logErr.Println(err.Error())
-		}
-		return false
-	}
+func ec2Client(region string) *ec2.EC2 {
+	clientsMutex.Lock()
+	defer clientsMutex.Unlock()
 
-	for _, instance := range result.InstanceStatuses {
-		if *instance.InstanceState.Name != "terminated" {
-			return true
-		}
+	if svcEc2[region] == nil {
+		svcEc2[region] = ec2.New(regionSession(region))
 	}
-
-	return false
+	return svcEc2[region]
 }
 
-func ec2VolumeExists(volumeId string) bool {
-	v("exists?", volumeId)
-	if svcEc2 == nil {
-		svcEc2 = ec2.New(sess)
-	}
-
-	input := &ec2.DescribeVolumeStatusInput{
-		VolumeIds: []*string{
-			&volumeId,
-		},
+func ec2Tags(tags []*ec2.Tag) map[string]string {
+	result := map[string]string{}
+	for _, tag := range tags {
+		result[aws.StringValue(tag.Key)] = aws.StringValue(tag.Value)
 	}
-	result, err := svcEc2.DescribeVolumeStatus(input)
-	if err != nil {
-		if aerr, ok := err.(awserr.Error); ok {
-			switch aerr.Code() {
-			case "InvalidVolume.NotFound":
-				return false
-			default:
-				logErr.Println(aerr.Code())
-				logErr.Println(aerr.Error())
-			}
-		} else {
-			// Print the error, cast err to awserr.Error to get the Code and
-			// Message from an error.
-			logErr.Println(err.Error())
-		}
-		return false
-	}
-
-	for _, volume := range result.VolumeStatuses {
-		if volume.VolumeStatus.String() != "" {
-			return true
-		}
-	}
-
-	return false
+	return result
 }
 
-func ec2NatGatewayExists(natgatewayId string) bool {
-	v("exists?", natgatewayId)
-	if svcEc2 == nil {
-		svcEc2 = ec2.New(sess)
-	}
-
-	input := &ec2.DescribeNatGatewaysInput{
-		NatGatewayIds: []*string{
-			&natgatewayId,
-		},
+// ec2CreateTags adds tags to the EC2 resource of id.
+func ec2CreateTags(region string, id string, tags map[string]string) error {
+	list := []*ec2.Tag{}
+	for key, value := range tags {
+		list = append(list, &ec2.Tag{Key: aws.String(key), Value: aws.String(value)})
 	}
-	result, err := svcEc2.DescribeNatGateways(input)
-	if err != nil {
-		if aerr, ok := err.(awserr.Error); ok {
-			switch aerr.Code() {
-			case "NatGatewayNotFound":
-				return false
-			default:
-				logErr.Println(aerr.Code())
-				logErr.Println(aerr.Error())
-			}
-		} else {
-			// Print the error, cast err to awserr.Error to get the Code and
-			// Message from an error.
-			logErr.Println(err.Error())
-		}
-		return false
-	}
-
-	for _, natgateway := range result.NatGateways {
-		switch *natgateway.State {
-		case "deleted", "deleting":
-			return false
-		default:
-			return true
-		}
-	}
-
-	return false
+	_, err := ec2Client(region).CreateTags(&ec2.CreateTagsInput{
+		Resources: []*string{aws.String(id)},
+		Tags:      list,
+	})
+	return err
 }
 
-func ec2SubnetExists(subnetId string) bool {
-	v("exists?", subnetId)
-	if svcEc2 == nil {
-		svcEc2 = ec2.New(sess)
-	}
-
-	input := &ec2.DescribeSubnetsInput{
-		SubnetIds: []*string{
-			&subnetId,
-		},
-	}
-	result, err := svcEc2.DescribeSubnets(input)
-	if err != nil {
-		if aerr, ok := err.(awserr.Error); ok {
-			switch aerr.Code() {
-			case "InvalidSubnetID.NotFound":
-				return false
-			default:
-				logErr.Println(aerr.Code())
-				logErr.Println(aerr.Error())
-			}
-		} else {
-			// Print the error, cast err to awserr.Error to get the Code and
-			// Message from an error.
-			logErr.Println(err.Error())
-		}
-		return false
-	}
-
-	for _, subnet := range result.Subnets {
-		// exclude default subnet
-		if *subnet.DefaultForAz {
-			return false
-		}
-		switch *subnet.State {
-		case "deleted", "deleting":
-			return false
-		default:
-			return true
-		}
-	}
-
-	return false
-}
-
-func isDefaultVpc(vpcId string) bool {
-	v("exists?", vpcId)
-	if svcEc2 == nil {
-		svcEc2 = ec2.New(sess)
-	}
-
-	input := &ec2.DescribeVpcsInput{
-		VpcIds: []*string{
-			&vpcId,
-		},
-	}
-	result, err := svcEc2.DescribeVpcs(input)
-	if err != nil {
-		if aerr, ok := err.(awserr.Error); ok {
-			switch aerr.Code() {
-			case "InvalidVpcID.NotFound":
-				return false
-			default:
-				logErr.Println(aerr.Code())
-				logErr.Println(aerr.Error())
-			}
-		} else {
-			// Print the error, cast err to awserr.Error to get the Code and
-			// Message from an error.
-			logErr.Println(err.Error())
-		}
-		return false
-	}
-
-	for _, vpc := range result.Vpcs {
-		// filter out default VPC
-		if *vpc.IsDefault {
-			return true
-		}
-	}
-
-	return false
-}
-
-func ec2VpcExists(vpcId string) bool {
-	v("exists?", vpcId)
-	if svcEc2 == nil {
-		svcEc2 = ec2.New(sess)
-	}
-
-	input := &ec2.DescribeVpcsInput{
-		VpcIds: []*string{
-			&vpcId,
-		},
-	}
-	result, err := svcEc2.DescribeVpcs(input)
-	if err != nil {
-		if aerr, ok := err.(awserr.Error); ok {
-			switch aerr.Code() {
-			case "InvalidVpcID.NotFound":
-				return false
-			default:
-				logErr.Println(aerr.Code())
-				logErr.Println(aerr.Error())
-			}
-		} else {
-			// Print the error, cast err to awserr.Error to get the Code and
-			// Message from an error.
-			logErr.Println(err.Error())
-		}
-		return false
-	}
-
-	for _, vpc := range result.Vpcs {
-		// filter out default VPC
-		if *vpc.IsDefault {
-			return false
-		}
-		switch *vpc.State {
-		case "deleted", "deleting":
-			return false
-		default:
-			return true
-		}
-	}
-
-	return false
-}
-
-func ec2EIPExists(addressId string) bool {
-	v("exists?", addressId)
-	if svcEc2 == nil {
-		svcEc2 = ec2.New(sess)
-	}
-
-	input := &ec2.DescribeAddressesInput{
-		PublicIps: []*string{
-			&addressId,
-		},
-	}
-	result, err := svcEc2.DescribeAddresses(input)
-	if err != nil {
-		if aerr, ok := err.(awserr.Error); ok {
-			switch aerr.Code() {
-			case "InvalidParameterValue":
-				return false
-			case "InvalidAddress.NotFound":
-				return false
-			default:
-				logErr.Println(aerr.Code())
-				logErr.Println(aerr.Error())
-			}
-		} else {
-			// Print the error, cast err to awserr.Error to get the Code and
-			// Message from an error.
-			logErr.Println(err.Error())
-		}
-		return false
-	}
-
-	for _, address := range result.Addresses {
-		if *address.PublicIp != "" {
-			return true
-		}
-	}
-
-	return false
-}
-
-func ec2RouteTableExists(routeTableId string) bool {
-	v("exists?", routeTableId)
-	if svcEc2 == nil {
-		svcEc2 = ec2.New(sess)
-	}
-
-	input := &ec2.DescribeRouteTablesInput{
-		RouteTableIds: []*string{
-			&routeTableId,
-		},
-	}
-	result, err := svcEc2.DescribeRouteTables(input)
-	if err != nil {
-		if aerr, ok := err.(awserr.Error); ok {
-			switch aerr.Code() {
-			case "InvalidParameterValue":
-				return false
-			case "InvalidRouteTableID.NotFound":
-				return false
-			default:
-				logErr.Println(aerr.Code())
-				logErr.Println(aerr.Error())
-			}
-		} else {
-			// Print the error, cast err to awserr.Error to get the Code and
-			// Message from an error.
-			logErr.Println(err.Error())
-		}
-		return false
-	}
-
-	for range result.RouteTables {
-		return true
-	}
-
-	return false
-}
-
-func ec2SecurityGroupExists(securityGroupId string) bool {
-	v("exists?", securityGroupId)
-	if svcEc2 == nil {
-		svcEc2 = ec2.New(sess)
-	}
-
-	input := &ec2.DescribeSecurityGroupsInput{
-		GroupIds: []*string{
-			&securityGroupId,
-		},
-	}
-	result, err := svcEc2.DescribeSecurityGroups(input)
-	if err != nil {
-		if aerr, ok := err.(awserr.Error); ok {
-			switch aerr.Code() {
-			case "InvalidGroupId.Malformed":
-				return false
-			case "InvalidGroup.NotFound":
-				return false
-			default:
-				logErr.Println(aerr.Code())
-				logErr.Println(aerr.Error())
-			}
-		} else {
-			// Print the error, cast err to awserr.Error to get the Code and
-			// Message from an error.
-			logErr.Println(err.Error())
-		}
-		return false
-	}
-
-	for _, group := range result.SecurityGroups {
-		// skip securityGroup of the default VPC
-		if isDefaultVpc(*group.VpcId) {
-			return false
-		} else {
-			return true
-		}
-	}
-
-	return false
-}
-
-func ec2NetworkInterfaceExists(networkInterfaceId string) bool {
-	v("exists?", networkInterfaceId)
-	if svcEc2 == nil {
-		svcEc2 = ec2.New(sess)
-	}
-
-	input := &ec2.DescribeNetworkInterfacesInput{
-		NetworkInterfaceIds: []*string{
-			&networkInterfaceId,
-		},
-	}
-	result, err := svcEc2.DescribeNetworkInterfaces(input)
-	if err != nil {
-		if aerr, ok := err.(awserr.Error); ok {
-			switch aerr.Code() {
-			case "InvalidNetworkInterfaceID.NotFound":
-				return false
-			default:
-				logErr.Println(aerr.Code())
-				logErr.Println(aerr.Error())
-			}
-		} else {
-			logErr.Println(err.Error())
-		}
-		return false
-	}
-
-	for range result.NetworkInterfaces {
-		return true
-	}
-
-	return false
-}
-
-func ec2InternetGatewayExists(internetGatewayId string) bool {
-	v("exists?", internetGatewayId)
-	if svcEc2 == nil {
-		svcEc2 = ec2.New(sess)
-	}
-
-	input := &ec2.DescribeInternetGatewaysInput{
-		InternetGatewayIds: []*string{
-			&internetGatewayId,
-		},
-	}
-	result, err := svcEc2.DescribeInternetGateways(input)
-	if err != nil {
-		if aerr, ok := err.(awserr.Error); ok {
-			switch aerr.Code() {
-			case "InvalidInternetGatewayID.NotFound":
-				return false
-			default:
-				logErr.Println(aerr.Code())
-				logErr.Println(aerr.Error())
-			}
-		} else {
-			// Print the error, cast err to awserr.Error to get the Code and
-			// Message from an error.
-			logErr.Println(err.Error())
-		}
-		return false
-	}
-
-	for _, internetGateway := range result.InternetGateways {
-		if *internetGateway.OwnerId != "" {
-			return true
-		}
-	}
-
-	return false
-}
-
-func ec2ImageExists(imageId string) bool {
-	v("exists?", imageId)
-	if svcEc2 == nil {
-		svcEc2 = ec2.New(sess)
-	}
-
-	input := &ec2.DescribeImagesInput{
-		ImageIds: []*string{
-			&imageId,
-		},
-	}
-	result, err := svcEc2.DescribeImages(input)
-	if err != nil {
-		if aerr, ok := err.(awserr.Error); ok {
-			switch aerr.Code() {
-			case "InvalidImageID.NotFound":
-				return false
-			case "InvalidAMIID.NotFound":
-				return false
-			default:
-				logErr.Println(aerr.Code())
-				logErr.Println(aerr.Error())
-			}
-		} else {
-			// Print the error, cast err to awserr.Error to get the Code and
-			// Message from an error.
-			logErr.Println(err.Error())
-		}
-		return false
-	}
-
-	for _, image := range result.Images {
-		if *image.Public {
-			logOut.Println(imageId, "is public, skipping.")
-			return false
-		}
-
-		switch *image.State {
-		case "deleted", "deleting":
-			return false
-		default:
-			return true
-		}
-	}
-
-	return false
+// ec2Tag is the Tag of the EC2 resources whose name is their id.
+func ec2Tag(resource *Resource, tags map[string]string) error {
+	return ec2CreateTags(resource.Region, resource.Name, tags)
 }
@@ -0,0 +1,108 @@
+package main
+
+import (
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/ec2"
+)
+
+func init() {
+	registerResourceType("AWS::EC2::DHCPOptions", &resourceHandler{
+		CreatedBy:    []string{"CreateDhcpOptions"},
+		ArnTypes:     []string{"ec2:dhcp-options"},
+		TrailIdKeys:  []string{"dhcpOptionsId"},
+		NukeType:     "EC2DHCPOption",
+		Exists:       ec2DhcpOptionsExists,
+		ExistsBatch:  ec2DhcpOptionsExistsBatch,
+		BatchSize:    ec2BatchSize,
+		Describe:     ec2DhcpOptionsDescribe,
+		Delete:       ec2DhcpOptionsDelete,
+		DeleteScript: ec2DhcpOptionsDeleteScript,
+		Tag:          ec2Tag,
+		DeleteAfter: []string{
+			"AWS::EC2::VPC",
+		},
+	})
+}
+
+func ec2DhcpOptionsExists(resource *Resource) (bool, error) {
+	found, errs := ec2DhcpOptionsExistsBatch(resource.Region, []string{resource.Name})
+	return found[resource.Name], errs[resource.Name]
+}
+
+func ec2DhcpOptionsExistsBatch(region string, dhcpOptionsIds []string) (map[string]bool, map[string]error) {
+	v("exists?", dhcpOptionsIds)
+	svc := ec2Client(region)
+	result := map[string]bool{}
+
+	input := &ec2.DescribeDhcpOptionsInput{
+		Filters: ec2Filter("dhcp-options-id", dhcpOptionsIds),
+	}
+	err := svc.DescribeDhcpOptionsPages(input,
+		func(page *ec2.DescribeDhcpOptionsOutput, lastPage bool) bool {
+			for _, dhcpOptions := range page.DhcpOptions {
+				result[*dhcpOptions.DhcpOptionsId] = true
+			}
+			return true
+		})
+	if err != nil {
+		return nil, batchErrors(dhcpOptionsIds, err)
+	}
+
+	return result, nil
+}
+
+func ec2DhcpOptionsDescribe(resource *Resource) (*ResourceDetails, error) {
+	svc := ec2Client(resource.Region)
+
+	result, err := svc.DescribeDhcpOptions(&ec2.DescribeDhcpOptionsInput{
+		DhcpOptionsIds: []*string{&resource.Name},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	for _, dhcpOptions := range result.DhcpOptions {
+		return &ResourceDetails{
+			Owner: aws.StringValue(dhcpOptions.OwnerId),
+			Tags:  ec2Tags(dhcpOptions.Tags),
+		}, nil
+	}
+
+	return nil, nil
+}
+
+func ec2DhcpOptionsDelete(resource *Resource) error {
+	svc := ec2Client(resource.Region)
+
+	// VPCs still using the options set go back to the default one
+	vpcs, err := svc.DescribeVpcs(&ec2.DescribeVpcsInput{
+		Filters: ec2Filter("dhcp-options-id", []string{resource.Name}),
+	})
+	if err != nil {
+		return err
+	}
+	for _, vpc := range vpcs.Vpcs {
+		_, err = svc.AssociateDhcpOptions(&ec2.AssociateDhcpOptionsInput{
+			DhcpOptionsId: aws.String("default"),
+			VpcId:         vpc.VpcId,
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	_, err = svc.DeleteDhcpOptions(&ec2.DeleteDhcpOptionsInput{
+		DhcpOptionsId: &resource.Name,
+	})
+	return err
+}
+
+func ec2DhcpOptionsDeleteScript(resource *Resource, aws string) []string {
+	id := shellQuote(resource.Name)
+	return []string{
+		"for vpc in $(" + aws + " ec2 describe-vpcs --filters Name=dhcp-options-id,Values=" + id + " --query 'Vpcs[].VpcId' --output text); do",
+		"  " + aws + ` ec2 associate-dhcp-options --dhcp-options-id default --vpc-id "$vpc"`,
+		"done",
+		aws + " ec2 delete-dhcp-options --dhcp-options-id " + id,
+	}
+}
@@ -0,0 +1,160 @@
+package main
+
+import (
+	"github.com/aws/aws-sdk-go/service/ec2"
+	"strings"
+)
+
+func init() {
+	registerResourceType("AWS::EC2::EIP", &resourceHandler{
+		CreatedBy:    []string{"AllocateAddress"},
+		ArnTypes:     []string{"ec2:elastic-ip"},
+		NukeType:     "EC2Address",
+		Canonical:    ec2EIPPublicIp,
+		Exists:       ec2EIPExists,
+		ExistsBatch:  ec2EIPExistsBatch,
+		BatchSize:    ec2BatchSize,
+		Describe:     ec2EIPDescribe,
+		Delete:       ec2EIPDelete,
+		DeleteScript: ec2EIPDeleteScript,
+		Tag:          ec2EIPTag,
+		DeleteAfter: []string{
+			"AWS::EC2::Instance",
+			"AWS::EC2::NatGateway",
+		},
+	})
+}
+
+// ec2EIPPublicIp returns the public IP of an EIP found by tags, by its
+// allocation id, as CloudTrail reports it.
+func ec2EIPPublicIp(region string, name string) string {
+	if !strings.HasPrefix(name, "eipalloc-") {
+		return name
+	}
+
+	result, err := ec2Client(region).DescribeAddresses(&ec2.DescribeAddressesInput{
+		AllocationIds: []*string{&name},
+	})
+	if err != nil {
+		logErr.Println("Got error describing", name)
+		logErr.Println(err.Error())
+		return name
+	}
+	for _, address := range result.Addresses {
+		if address.PublicIp != nil {
+			return *address.PublicIp
+		}
+	}
+	return name
+}
+
+func ec2EIPExists(resource *Resource) (bool, error) {
+	found, errs := ec2EIPExistsBatch(resource.Region, []string{resource.Name})
+	return found[resource.Name], errs[resource.Name]
+}
+
+func ec2EIPExistsBatch(region string, addressIds []string) (map[string]bool, map[string]error) {
+	v("exists?", addressIds)
+	svc := ec2Client(region)
+	result := map[string]bool{}
+
+	input := &ec2.DescribeAddressesInput{
+		Filters: ec2Filter("public-ip", addressIds),
+	}
+	addresses, err := svc.DescribeAddresses(input)
+	if err != nil {
+		return nil, batchErrors(addressIds, err)
+	}
+
+	for _, address := range addresses.Addresses {
+		if *address.PublicIp != "" {
+			result[*address.PublicIp] = true
+		}
+	}
+
+	return result, nil
+}
+
+func ec2EIPDescribe(resource *Resource) (*ResourceDetails, error) {
+	svc := ec2Client(resource.Region)
+
+	result, err := svc.DescribeAddresses(&ec2.DescribeAddressesInput{
+		PublicIps: []*string{&resource.Name},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	for _, address := range result.Addresses {
+		return &ResourceDetails{
+			Tags: ec2Tags(address.Tags),
+		}, nil
+	}
+
+	return nil, nil
+}
+
+func ec2EIPDelete(resource *Resource) error {
+	svc := ec2Client(resource.Region)
+
+	result, err := svc.DescribeAddresses(&ec2.DescribeAddressesInput{
+		PublicIps: []*string{&resource.Name},
+	})
+	if err != nil {
+		return err
+	}
+
+	for _, address := range result.Addresses {
+		if address.AssociationId != nil {
+			_, err = svc.DisassociateAddress(&ec2.DisassociateAddressInput{
+				AssociationId: address.AssociationId,
+			})
+			if err != nil {
+				return err
+			}
+		}
+
+		input := &ec2.ReleaseAddressInput{}
+		if address.AllocationId != nil {
+			input.AllocationId = address.AllocationId
+		} else {
+			// EC2-Classic
+			input.PublicIp = address.PublicIp
+		}
+		if _, err = svc.ReleaseAddress(input); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func ec2EIPDeleteScript(resource *Resource, aws string) []string {
+	describe := aws + " ec2 describe-addresses --public-ips " + shellQuote(resource.Name) + " --output text --query "
+	return []string{
+		"association=$(" + describe + "'Addresses[0].AssociationId')",
+		`if [ "$association" != None ]; then ` + aws + ` ec2 disassociate-address --association-id "$association"; fi`,
+		aws + " ec2 release-address --allocation-id \"$(" + describe + "'Addresses[0].AllocationId')\"",
+	}
+}
+
+// EIPs are tagged by allocation id, CloudTrail reports their public IP.
+func ec2EIPTag(resource *Resource, tags map[string]string) error {
+	result, err := ec2Client(resource.Region).DescribeAddresses(&ec2.DescribeAddressesInput{
+		PublicIps: []*string{&resource.Name},
+	})
+	if err != nil {
+		return err
+	}
+
+	for _, address := range result.Addresses {
+		if address.AllocationId == nil {
+			// EC2-Classic addresses have no tags
+			return errTagNotSupported
+		}
+		if err := ec2CreateTags(resource.Region, *address.AllocationId, tags); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,162 @@
+package main
+
+import (
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/ec2"
+	"sort"
+	"time"
+)
+
+// backendImage is reported in Resource.FoundBy for the snapshots found as
+// the block devices of an AMI.
+const backendImage = "image"
+
+func init() {
+	registerResourceType("AWS::EC2::Ami", &resourceHandler{
+		CreatedBy:    []string{"CreateImage", "CopyImage", "ImportImage", "RegisterImage"},
+		ArnTypes:     []string{"ec2:image"},
+		TrailIdKeys:  []string{"imageId"},
+		NukeType:     "EC2Image",
+		Exists:       ec2ImageExists,
+		ExistsBatch:  ec2ImageExistsBatch,
+		BatchSize:    ec2BatchSize,
+		Describe:     ec2ImageDescribe,
+		Delete:       ec2ImageDelete,
+		DeleteScript: scriptCommand("ec2 deregister-image --image-id"),
+		Tag:          ec2Tag,
+	})
+}
+
+func ec2ImageExists(resource *Resource) (bool, error) {
+	found, errs := ec2ImageExistsBatch(resource.Region, []string{resource.Name})
+	return found[resource.Name], errs[resource.Name]
+}
+
+func ec2ImageExistsBatch(region string, imageIds []string) (map[string]bool, map[string]error) {
+	v("exists?", imageIds)
+	svc := ec2Client(region)
+	result := map[string]bool{}
+
+	input := &ec2.DescribeImagesInput{
+		Filters: ec2Filter("image-id", imageIds),
+	}
+	images, err := svc.DescribeImages(input)
+	if err != nil {
+		return nil, batchErrors(imageIds, err)
+	}
+
+	for _, image := range images.Images {
+		if *image.Public {
+			logOut.Println(*image.ImageId, "is public, skipping.")
+			continue
+		}
+
+		switch *image.State {
+		case "deleted", "deleting":
+		default:
+			result[*image.ImageId] = true
+		}
+	}
+
+	return result, nil
+}
+
+func ec2ImageDescribe(resource *Resource) (*ResourceDetails, error) {
+	svc := ec2Client(resource.Region)
+
+	result, err := svc.DescribeImages(&ec2.DescribeImagesInput{
+		ImageIds: []*string{&resource.Name},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	for _, image := range result.Images {
+		details := &ResourceDetails{
+			Owner: aws.StringValue(image.OwnerId),
+			Tags:  ec2Tags(image.Tags),
+		}
+		if creationTime, err := time.Parse(time.RFC3339, aws.StringValue(image.CreationDate)); err == nil {
+			details.CreationTime = &creationTime
+		}
+		return details, nil
+	}
+
+	return nil, nil
+}
+
+func ec2ImageDelete(resource *Resource) error {
+	svc := ec2Client(resource.Region)
+
+	_, err := svc.DeregisterImage(&ec2.DeregisterImageInput{
+		ImageId: &resource.Name,
+	})
+	return err
+}
+
+// searchImageSnapshots returns the EBS snapshots backing the AMIs among
+// resources. Deregistering an AMI leaves its snapshots, they are reported as
+// children of the AMI and deleted after it.
+func searchImageSnapshots(resources []*Resource) []*Resource {
+	images := map[string]map[string]*Resource{}
+	regions := []string{}
+	for _, resource := range resources {
+		if resource.Type != "AWS::EC2::Ami" {
+			continue
+		}
+		if images[resource.Region] == nil {
+			images[resource.Region] = map[string]*Resource{}
+			regions = append(regions, resource.Region)
+		}
+		images[resource.Region][resource.Name] = resource
+	}
+	sort.Strings(regions)
+
+	found := []*Resource{}
+	for _, region := range regions {
+		imageIds := []string{}
+		for imageId := range images[region] {
+			imageIds = append(imageIds, imageId)
+		}
+		sort.Strings(imageIds)
+
+		for start := 0; start < len(imageIds); start += ec2BatchSize {
+			end := start + ec2BatchSize
+			if end > len(imageIds) {
+				end = len(imageIds)
+			}
+			result, err := ec2Client(region).DescribeImages(&ec2.DescribeImagesInput{
+				Filters: ec2Filter("image-id", imageIds[start:end]),
+			})
+			if err != nil {
+				logErr.Println("Got error describing the AMIs in", region)
+				logErr.Println(err.Error())
+				continue
+			}
+
+			for _, image := range result.Images {
+				parent := images[region][aws.StringValue(image.ImageId)]
+				for _, mapping := range image.BlockDeviceMappings {
+					if mapping.Ebs == nil || mapping.Ebs.SnapshotId == nil {
+						continue
+					}
+					v("└──", region, "AWS::EC2::Snapshot", *mapping.Ebs.SnapshotId, "of", parent.Name)
+					found = append(found, &Resource{
+						Type:       "AWS::EC2::Snapshot",
+						Name:       *mapping.Ebs.SnapshotId,
+						Region:     region,
+						Principal:  parent.Principal,
+						Chain:      append([]string{}, parent.Chain...),
+						Users:      append([]string{}, parent.Users...),
+						startTime:  parent.startTime,
+						FoundBy:    []string{backendImage},
+						Parent:     parent.Name,
+						ParentType: parent.Type,
+					})
+				}
+			}
+		}
+	}
+
+	return found
+}
@@ -0,0 +1,66 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+// The snapshots of an AMI are screened like any resource, then kept or left
+// with their AMI: deregistering a protected AMI's snapshots would break it.
+func TestImageSnapshotsFollowTheirAMI(t *testing.T) {
+	defer func(p *policy) { protectionPolicy = p }(protectionPolicy)
+	var err error
+	protectionPolicy, err = testPolicy(t, "rules:\n- name: golden\n  action: deny\n  id: ami-golden\n")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	start := time.Date(2019, 1, 14, 7, 0, 0, 0, time.UTC)
+	before, after := start.Add(-24*time.Hour), start.Add(time.Hour)
+	ami := func(name string, created time.Time) *Resource {
+		return &Resource{Type: "AWS::EC2::Ami", Name: name, Region: "us-east-1", EventName: "CreateImage",
+			Details: &ResourceDetails{CreationTime: &created}}
+	}
+	// Snapshots are created with their AMI
+	snapshot := func(name string, parent *Resource) *Resource {
+		return &Resource{Type: "AWS::EC2::Snapshot", Name: name, Region: "us-east-1", FoundBy: []string{backendImage},
+			Parent: parent.Name, ParentType: parent.Type, Details: &ResourceDetails{CreationTime: parent.Details.CreationTime}}
+	}
+
+	images := []*Resource{ami("ami-golden", after), ami("ami-old", before), ami("ami-new", after)}
+	snapshots := []*Resource{
+		snapshot("snap-golden", images[0]),
+		snapshot("snap-old", images[1]),
+		snapshot("snap-new", images[2]),
+	}
+	// Without creation time, the snapshots are taken as created after start
+	for _, parent := range images[:2] {
+		unknown := snapshot("snap-unknown-"+parent.Name, parent)
+		unknown.Details = &ResourceDetails{}
+		snapshots = append(snapshots, unknown)
+	}
+
+	candidates, protected, preexisting := screenResources(images, start)
+	if len(protected) != 1 || protected[0].Name != "ami-golden" || len(preexisting) != 1 || preexisting[0].Name != "ami-old" {
+		t.Fatalf("AMIs protected %v, preexisting %v", protected, preexisting)
+	}
+	screenedSnapshots, _, _ := screenResources(snapshots, start)
+	kept, orphans := filterOrphans(append(candidates, screenedSnapshots...))
+
+	deleted := map[string]bool{}
+	for _, resource := range kept {
+		deleted[resource.Name] = true
+	}
+	if !deleted["ami-new"] || !deleted["snap-new"] || len(kept) != 2 {
+		t.Errorf("deleted %v, want the new AMI and its snapshot", deleted)
+	}
+	for _, orphan := range orphans {
+		if orphan.Parent != "ami-golden" && orphan.Parent != "ami-old" {
+			t.Errorf("%s of %s is an orphan", orphan.Name, orphan.Parent)
+		}
+	}
+	// snap-old is preexisting itself
+	if len(orphans) != 3 {
+		t.Errorf("%d orphans, want the snapshots of the protected and preexisting AMIs", len(orphans))
+	}
+}
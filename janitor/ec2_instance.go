@@ -0,0 +1,115 @@
+package main
+
+import (
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/ec2"
+)
+
+func init() {
+	registerResourceType("AWS::EC2::Instance", &resourceHandler{
+		CreatedBy:    []string{"RunInstances"},
+		ArnTypes:     []string{"ec2:instance"},
+		TrailIdKeys:  []string{"instanceId"},
+		NukeType:     "EC2Instance",
+		Exists:       ec2InstanceExists,
+		ExistsBatch:  ec2InstanceExistsBatch,
+		BatchSize:    ec2BatchSize,
+		Describe:     ec2InstanceDescribe,
+		Delete:       ec2InstanceDelete,
+		DeleteScript: ec2InstanceDeleteScript,
+		Tag:          ec2Tag,
+		Quarantine:   ec2InstanceQuarantine,
+	})
+}
+
+func ec2InstanceExists(resource *Resource) (bool, error) {
+	found, errs := ec2InstanceExistsBatch(resource.Region, []string{resource.Name})
+	return found[resource.Name], errs[resource.Name]
+}
+
+func ec2InstanceExistsBatch(region string, instanceIds []string) (map[string]bool, map[string]error) {
+	v("exists?", instanceIds)
+	svc := ec2Client(region)
+	result := map[string]bool{}
+
+	input := &ec2.DescribeInstancesInput{
+		Filters: ec2Filter("instance-id", instanceIds),
+	}
+	err := svc.DescribeInstancesPages(input,
+		func(page *ec2.DescribeInstancesOutput, lastPage bool) bool {
+			for _, reservation := range page.Reservations {
+				for _, instance := range reservation.Instances {
+					if *instance.State.Name != "terminated" {
+						result[*instance.InstanceId] = true
+					}
+				}
+			}
+			return true
+		})
+	if err != nil {
+		return nil, batchErrors(instanceIds, err)
+	}
+
+	return result, nil
+}
+
+func ec2InstanceDescribe(resource *Resource) (*ResourceDetails, error) {
+	svc := ec2Client(resource.Region)
+
+	result, err := svc.DescribeInstances(&ec2.DescribeInstancesInput{
+		InstanceIds: []*string{&resource.Name},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	for _, reservation := range result.Reservations {
+		for _, instance := range reservation.Instances {
+			state := ""
+			if instance.State != nil {
+				state = aws.StringValue(instance.State.Name)
+			}
+			return &ResourceDetails{
+				Owner:        aws.StringValue(reservation.OwnerId),
+				Tags:         ec2Tags(instance.Tags),
+				CreationTime: instance.LaunchTime,
+				InstanceType: aws.StringValue(instance.InstanceType),
+				State:        state,
+			}, nil
+		}
+	}
+
+	return nil, nil
+}
+
+func ec2InstanceDelete(resource *Resource) error {
+	svc := ec2Client(resource.Region)
+
+	_, err := svc.TerminateInstances(&ec2.TerminateInstancesInput{
+		InstanceIds: []*string{&resource.Name},
+	})
+	if err != nil {
+		return err
+	}
+
+	// Wait for termination so the ENIs and volumes attached to the
+	// instance are released before their turn comes.
+	return svc.WaitUntilInstanceTerminated(&ec2.DescribeInstancesInput{
+		InstanceIds: []*string{&resource.Name},
+	})
+}
+
+func ec2InstanceDeleteScript(resource *Resource, aws string) []string {
+	id := shellQuote(resource.Name)
+	return []string{
+		aws + " ec2 terminate-instances --instance-ids " + id,
+		aws + " ec2 wait instance-terminated --instance-ids " + id,
+	}
+}
+
+func ec2InstanceQuarantine(resource *Resource) error {
+	_, err := ec2Client(resource.Region).StopInstances(&ec2.StopInstancesInput{
+		InstanceIds: []*string{&resource.Name},
+	})
+	return err
+}
@@ -0,0 +1,114 @@
+package main
+
+import (
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/ec2"
+)
+
+func init() {
+	registerResourceType("AWS::EC2::InternetGateway", &resourceHandler{
+		CreatedBy:    []string{"CreateInternetGateway"},
+		ArnTypes:     []string{"ec2:internet-gateway"},
+		TrailIdKeys:  []string{"internetGatewayId"},
+		NukeType:     "EC2InternetGateway",
+		Exists:       ec2InternetGatewayExists,
+		ExistsBatch:  ec2InternetGatewayExistsBatch,
+		BatchSize:    ec2BatchSize,
+		Describe:     ec2InternetGatewayDescribe,
+		Delete:       ec2InternetGatewayDelete,
+		DeleteScript: ec2InternetGatewayDeleteScript,
+		Tag:          ec2Tag,
+		DeleteAfter: []string{
+			"AWS::EC2::Instance",
+			"AWS::EC2::NatGateway",
+			"AWS::EC2::EIP",
+		},
+	})
+}
+
+func ec2InternetGatewayExists(resource *Resource) (bool, error) {
+	found, errs := ec2InternetGatewayExistsBatch(resource.Region, []string{resource.Name})
+	return found[resource.Name], errs[resource.Name]
+}
+
+func ec2InternetGatewayExistsBatch(region string, internetGatewayIds []string) (map[string]bool, map[string]error) {
+	v("exists?", internetGatewayIds)
+	svc := ec2Client(region)
+	result := map[string]bool{}
+
+	input := &ec2.DescribeInternetGatewaysInput{
+		Filters: ec2Filter("internet-gateway-id", internetGatewayIds),
+	}
+	err := svc.DescribeInternetGatewaysPages(input,
+		func(page *ec2.DescribeInternetGatewaysOutput, lastPage bool) bool {
+			for _, internetGateway := range page.InternetGateways {
+				if *internetGateway.OwnerId != "" {
+					result[*internetGateway.InternetGatewayId] = true
+				}
+			}
+			return true
+		})
+	if err != nil {
+		return nil, batchErrors(internetGatewayIds, err)
+	}
+
+	return result, nil
+}
+
+func ec2InternetGatewayDescribe(resource *Resource) (*ResourceDetails, error) {
+	svc := ec2Client(resource.Region)
+
+	result, err := svc.DescribeInternetGateways(&ec2.DescribeInternetGatewaysInput{
+		InternetGatewayIds: []*string{&resource.Name},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	for _, internetGateway := range result.InternetGateways {
+		return &ResourceDetails{
+			Owner: aws.StringValue(internetGateway.OwnerId),
+			Tags:  ec2Tags(internetGateway.Tags),
+		}, nil
+	}
+
+	return nil, nil
+}
+
+func ec2InternetGatewayDelete(resource *Resource) error {
+	svc := ec2Client(resource.Region)
+
+	result, err := svc.DescribeInternetGateways(&ec2.DescribeInternetGatewaysInput{
+		InternetGatewayIds: []*string{&resource.Name},
+	})
+	if err != nil {
+		return err
+	}
+
+	for _, internetGateway := range result.InternetGateways {
+		for _, attachment := range internetGateway.Attachments {
+			_, err = svc.DetachInternetGateway(&ec2.DetachInternetGatewayInput{
+				InternetGatewayId: &resource.Name,
+				VpcId:             attachment.VpcId,
+			})
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	_, err = svc.DeleteInternetGateway(&ec2.DeleteInternetGatewayInput{
+		InternetGatewayId: &resource.Name,
+	})
+	return err
+}
+
+func ec2InternetGatewayDeleteScript(resource *Resource, aws string) []string {
+	id := shellQuote(resource.Name)
+	return []string{
+		"for vpc in $(" + aws + " ec2 describe-internet-gateways --internet-gateway-ids " + id + " --query 'InternetGateways[0].Attachments[].VpcId' --output text); do",
+		"  " + aws + " ec2 detach-internet-gateway --internet-gateway-id " + id + ` --vpc-id "$vpc"`,
+		"done",
+		aws + " ec2 delete-internet-gateway --internet-gateway-id " + id,
+	}
+}
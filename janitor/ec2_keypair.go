@@ -0,0 +1,138 @@
+package main
+
+import (
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/ec2"
+	"strings"
+)
+
+func init() {
+	registerResourceType("AWS::EC2::KeyPair", &resourceHandler{
+		CreatedBy:    []string{"CreateKeyPair", "ImportKeyPair"},
+		ArnTypes:     []string{"ec2:key-pair"},
+		TrailIdKeys:  []string{"keyName"},
+		NukeType:     "EC2KeyPair",
+		Exists:       ec2KeyPairExists,
+		ExistsBatch:  ec2KeyPairExistsBatch,
+		BatchSize:    ec2BatchSize,
+		Describe:     ec2KeyPairDescribe,
+		Delete:       ec2KeyPairDelete,
+		DeleteScript: ec2KeyPairDeleteScript,
+		Tag:          ec2KeyPairTag,
+	})
+}
+
+// CloudTrail reports key pairs either by id (key-...) or by name.
+func isKeyPairId(keyPair string) bool {
+	return strings.HasPrefix(keyPair, "key-")
+}
+
+func ec2KeyPairFilter(keyPair string) []*ec2.Filter {
+	if isKeyPairId(keyPair) {
+		return ec2Filter("key-pair-id", []string{keyPair})
+	}
+	return ec2Filter("key-name", []string{keyPair})
+}
+
+func ec2KeyPairExists(resource *Resource) (bool, error) {
+	found, errs := ec2KeyPairExistsBatch(resource.Region, []string{resource.Name})
+	return found[resource.Name], errs[resource.Name]
+}
+
+func ec2KeyPairExistsBatch(region string, keyPairs []string) (map[string]bool, map[string]error) {
+	v("exists?", keyPairs)
+	svc := ec2Client(region)
+	result := map[string]bool{}
+	errs := map[string]error{}
+
+	ids := []string{}
+	names := []string{}
+	for _, keyPair := range keyPairs {
+		if isKeyPairId(keyPair) {
+			ids = append(ids, keyPair)
+		} else {
+			names = append(names, keyPair)
+		}
+	}
+
+	for filter, values := range map[string][]string{"key-pair-id": ids, "key-name": names} {
+		if len(values) == 0 {
+			continue
+		}
+		output, err := svc.DescribeKeyPairs(&ec2.DescribeKeyPairsInput{
+			Filters: ec2Filter(filter, values),
+		})
+		if err != nil {
+			for name, err := range batchErrors(values, err) {
+				errs[name] = err
+			}
+			continue
+		}
+		for _, keyPair := range output.KeyPairs {
+			result[aws.StringValue(keyPair.KeyPairId)] = true
+			result[aws.StringValue(keyPair.KeyName)] = true
+		}
+	}
+
+	return result, errs
+}
+
+func ec2KeyPairDescribe(resource *Resource) (*ResourceDetails, error) {
+	svc := ec2Client(resource.Region)
+
+	result, err := svc.DescribeKeyPairs(&ec2.DescribeKeyPairsInput{
+		Filters: ec2KeyPairFilter(resource.Name),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	for _, keyPair := range result.KeyPairs {
+		return &ResourceDetails{
+			Tags: ec2Tags(keyPair.Tags),
+		}, nil
+	}
+
+	return nil, nil
+}
+
+func ec2KeyPairDelete(resource *Resource) error {
+	svc := ec2Client(resource.Region)
+
+	input := &ec2.DeleteKeyPairInput{}
+	if isKeyPairId(resource.Name) {
+		input.KeyPairId = &resource.Name
+	} else {
+		input.KeyName = &resource.Name
+	}
+	_, err := svc.DeleteKeyPair(input)
+	return err
+}
+
+func ec2KeyPairDeleteScript(resource *Resource, aws string) []string {
+	if isKeyPairId(resource.Name) {
+		return []string{aws + " ec2 delete-key-pair --key-pair-id " + shellQuote(resource.Name)}
+	}
+	return []string{aws + " ec2 delete-key-pair --key-name " + shellQuote(resource.Name)}
+}
+
+// Key pairs are tagged by id, CloudTrail may report their name.
+func ec2KeyPairTag(resource *Resource, tags map[string]string) error {
+	if isKeyPairId(resource.Name) {
+		return ec2CreateTags(resource.Region, resource.Name, tags)
+	}
+
+	result, err := ec2Client(resource.Region).DescribeKeyPairs(&ec2.DescribeKeyPairsInput{
+		Filters: ec2KeyPairFilter(resource.Name),
+	})
+	if err != nil {
+		return err
+	}
+
+	for _, keyPair := range result.KeyPairs {
+		if err := ec2CreateTags(resource.Region, aws.StringValue(keyPair.KeyPairId), tags); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,78 @@
+package main
+
+import (
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/ec2"
+)
+
+func init() {
+	registerResourceType("AWS::EC2::LaunchTemplate", &resourceHandler{
+		CreatedBy:    []string{"CreateLaunchTemplate"},
+		ArnTypes:     []string{"ec2:launch-template"},
+		TrailIdKeys:  []string{"launchTemplateId"},
+		NukeType:     "EC2LaunchTemplate",
+		Exists:       ec2LaunchTemplateExists,
+		ExistsBatch:  ec2LaunchTemplateExistsBatch,
+		BatchSize:    ec2BatchSize,
+		Describe:     ec2LaunchTemplateDescribe,
+		Delete:       ec2LaunchTemplateDelete,
+		DeleteScript: scriptCommand("ec2 delete-launch-template --launch-template-id"),
+		Tag:          ec2Tag,
+	})
+}
+
+func ec2LaunchTemplateExists(resource *Resource) (bool, error) {
+	found, errs := ec2LaunchTemplateExistsBatch(resource.Region, []string{resource.Name})
+	return found[resource.Name], errs[resource.Name]
+}
+
+func ec2LaunchTemplateExistsBatch(region string, launchTemplateIds []string) (map[string]bool, map[string]error) {
+	v("exists?", launchTemplateIds)
+	svc := ec2Client(region)
+
+	// There is no filter on the id, and one unknown id fails the whole call.
+	return existsBisect(launchTemplateIds, func(launchTemplateIds []string) (map[string]bool, error) {
+		result := map[string]bool{}
+		err := svc.DescribeLaunchTemplatesPages(
+			&ec2.DescribeLaunchTemplatesInput{
+				LaunchTemplateIds: aws.StringSlice(launchTemplateIds),
+			},
+			func(page *ec2.DescribeLaunchTemplatesOutput, lastPage bool) bool {
+				for _, launchTemplate := range page.LaunchTemplates {
+					result[*launchTemplate.LaunchTemplateId] = true
+				}
+				return true
+			})
+		return result, err
+	})
+}
+
+func ec2LaunchTemplateDescribe(resource *Resource) (*ResourceDetails, error) {
+	svc := ec2Client(resource.Region)
+
+	result, err := svc.DescribeLaunchTemplates(&ec2.DescribeLaunchTemplatesInput{
+		LaunchTemplateIds: []*string{&resource.Name},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	for _, launchTemplate := range result.LaunchTemplates {
+		return &ResourceDetails{
+			Owner:        aws.StringValue(launchTemplate.CreatedBy),
+			Tags:         ec2Tags(launchTemplate.Tags),
+			CreationTime: launchTemplate.CreateTime,
+		}, nil
+	}
+
+	return nil, nil
+}
+
+func ec2LaunchTemplateDelete(resource *Resource) error {
+	svc := ec2Client(resource.Region)
+
+	_, err := svc.DeleteLaunchTemplate(&ec2.DeleteLaunchTemplateInput{
+		LaunchTemplateId: &resource.Name,
+	})
+	return err
+}
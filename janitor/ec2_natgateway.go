@@ -0,0 +1,96 @@
+package main
+
+import (
+	"github.com/aws/aws-sdk-go/service/ec2"
+)
+
+func init() {
+	registerResourceType("AWS::EC2::NatGateway", &resourceHandler{
+		CreatedBy:    []string{"CreateNatGateway"},
+		ArnTypes:     []string{"ec2:natgateway"},
+		TrailIdKeys:  []string{"natGatewayId"},
+		NukeType:     "EC2NATGateway",
+		Exists:       ec2NatGatewayExists,
+		ExistsBatch:  ec2NatGatewayExistsBatch,
+		BatchSize:    ec2BatchSize,
+		Describe:     ec2NatGatewayDescribe,
+		Delete:       ec2NatGatewayDelete,
+		DeleteScript: ec2NatGatewayDeleteScript,
+		Tag:          ec2Tag,
+	})
+}
+
+func ec2NatGatewayExists(resource *Resource) (bool, error) {
+	found, errs := ec2NatGatewayExistsBatch(resource.Region, []string{resource.Name})
+	return found[resource.Name], errs[resource.Name]
+}
+
+func ec2NatGatewayExistsBatch(region string, natgatewayIds []string) (map[string]bool, map[string]error) {
+	v("exists?", natgatewayIds)
+	svc := ec2Client(region)
+	result := map[string]bool{}
+
+	input := &ec2.DescribeNatGatewaysInput{
+		Filter: ec2Filter("nat-gateway-id", natgatewayIds),
+	}
+	err := svc.DescribeNatGatewaysPages(input,
+		func(page *ec2.DescribeNatGatewaysOutput, lastPage bool) bool {
+			for _, natgateway := range page.NatGateways {
+				switch *natgateway.State {
+				case "deleted", "deleting":
+				default:
+					result[*natgateway.NatGatewayId] = true
+				}
+			}
+			return true
+		})
+	if err != nil {
+		return nil, batchErrors(natgatewayIds, err)
+	}
+
+	return result, nil
+}
+
+func ec2NatGatewayDescribe(resource *Resource) (*ResourceDetails, error) {
+	svc := ec2Client(resource.Region)
+
+	result, err := svc.DescribeNatGateways(&ec2.DescribeNatGatewaysInput{
+		NatGatewayIds: []*string{&resource.Name},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	for _, natgateway := range result.NatGateways {
+		return &ResourceDetails{
+			Tags:         ec2Tags(natgateway.Tags),
+			CreationTime: natgateway.CreateTime,
+		}, nil
+	}
+
+	return nil, nil
+}
+
+func ec2NatGatewayDelete(resource *Resource) error {
+	svc := ec2Client(resource.Region)
+
+	_, err := svc.DeleteNatGateway(&ec2.DeleteNatGatewayInput{
+		NatGatewayId: &resource.Name,
+	})
+	if err != nil {
+		return err
+	}
+
+	// The EIP and the subnet stay in use until the NAT gateway is gone.
+	return svc.WaitUntilNatGatewayDeleted(&ec2.DescribeNatGatewaysInput{
+		NatGatewayIds: []*string{&resource.Name},
+	})
+}
+
+func ec2NatGatewayDeleteScript(resource *Resource, aws string) []string {
+	id := shellQuote(resource.Name)
+	return []string{
+		aws + " ec2 delete-nat-gateway --nat-gateway-id " + id,
+		aws + " ec2 wait nat-gateway-deleted --nat-gateway-ids " + id,
+	}
+}
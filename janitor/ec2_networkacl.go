@@ -0,0 +1,134 @@
+package main
+
+import (
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/ec2"
+)
+
+func init() {
+	registerResourceType("AWS::EC2::NetworkAcl", &resourceHandler{
+		CreatedBy:    []string{"CreateNetworkAcl"},
+		ArnTypes:     []string{"ec2:network-acl"},
+		TrailIdKeys:  []string{"networkAclId"},
+		NukeType:     "EC2NetworkACL",
+		Exists:       ec2NetworkAclExists,
+		ExistsBatch:  ec2NetworkAclExistsBatch,
+		BatchSize:    ec2BatchSize,
+		Describe:     ec2NetworkAclDescribe,
+		Delete:       ec2NetworkAclDelete,
+		DeleteScript: ec2NetworkAclDeleteScript,
+		Tag:          ec2Tag,
+		DeleteAfter: []string{
+			"AWS::EC2::Subnet",
+		},
+	})
+}
+
+func ec2NetworkAclExists(resource *Resource) (bool, error) {
+	found, errs := ec2NetworkAclExistsBatch(resource.Region, []string{resource.Name})
+	return found[resource.Name], errs[resource.Name]
+}
+
+func ec2NetworkAclExistsBatch(region string, networkAclIds []string) (map[string]bool, map[string]error) {
+	v("exists?", networkAclIds)
+	svc := ec2Client(region)
+	result := map[string]bool{}
+
+	input := &ec2.DescribeNetworkAclsInput{
+		Filters: ec2Filter("network-acl-id", networkAclIds),
+	}
+	err := svc.DescribeNetworkAclsPages(input,
+		func(page *ec2.DescribeNetworkAclsOutput, lastPage bool) bool {
+			for _, networkAcl := range page.NetworkAcls {
+				// the default ACL goes away with its VPC
+				if !*networkAcl.IsDefault {
+					result[*networkAcl.NetworkAclId] = true
+				}
+			}
+			return true
+		})
+	if err != nil {
+		return nil, batchErrors(networkAclIds, err)
+	}
+
+	return result, nil
+}
+
+func ec2NetworkAclDescribe(resource *Resource) (*ResourceDetails, error) {
+	svc := ec2Client(resource.Region)
+
+	result, err := svc.DescribeNetworkAcls(&ec2.DescribeNetworkAclsInput{
+		NetworkAclIds: []*string{&resource.Name},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	for _, networkAcl := range result.NetworkAcls {
+		return &ResourceDetails{
+			Owner: aws.StringValue(networkAcl.OwnerId),
+			Tags:  ec2Tags(networkAcl.Tags),
+		}, nil
+	}
+
+	return nil, nil
+}
+
+func ec2NetworkAclDelete(resource *Resource) error {
+	svc := ec2Client(resource.Region)
+
+	result, err := svc.DescribeNetworkAcls(&ec2.DescribeNetworkAclsInput{
+		NetworkAclIds: []*string{&resource.Name},
+	})
+	if err != nil {
+		return err
+	}
+
+	// Subnets still associated go back to the default ACL of the VPC
+	for _, networkAcl := range result.NetworkAcls {
+		if len(networkAcl.Associations) == 0 {
+			continue
+		}
+
+		defaults, err := svc.DescribeNetworkAcls(&ec2.DescribeNetworkAclsInput{
+			Filters: append(
+				ec2Filter("vpc-id", []string{*networkAcl.VpcId}),
+				ec2Filter("default", []string{"true"})...,
+			),
+		})
+		if err != nil {
+			return err
+		}
+		if len(defaults.NetworkAcls) == 0 {
+			continue
+		}
+
+		for _, association := range networkAcl.Associations {
+			_, err = svc.ReplaceNetworkAclAssociation(&ec2.ReplaceNetworkAclAssociationInput{
+				AssociationId: association.NetworkAclAssociationId,
+				NetworkAclId:  defaults.NetworkAcls[0].NetworkAclId,
+			})
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	_, err = svc.DeleteNetworkAcl(&ec2.DeleteNetworkAclInput{
+		NetworkAclId: &resource.Name,
+	})
+	return err
+}
+
+func ec2NetworkAclDeleteScript(resource *Resource, aws string) []string {
+	id := shellQuote(resource.Name)
+	describe := aws + " ec2 describe-network-acls --network-acl-ids " + id + " --output text --query "
+	return []string{
+		"vpc=$(" + describe + "'NetworkAcls[0].VpcId')",
+		"default=$(" + aws + ` ec2 describe-network-acls --filters "Name=vpc-id,Values=$vpc" Name=default,Values=true --query 'NetworkAcls[0].NetworkAclId' --output text)`,
+		"for association in $(" + describe + "'NetworkAcls[0].Associations[].NetworkAclAssociationId'); do",
+		"  " + aws + ` ec2 replace-network-acl-association --association-id "$association" --network-acl-id "$default"`,
+		"done",
+		aws + " ec2 delete-network-acl --network-acl-id " + id,
+	}
+}
@@ -0,0 +1,113 @@
+package main
+
+import (
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/ec2"
+)
+
+func init() {
+	registerResourceType("AWS::EC2::NetworkInterface", &resourceHandler{
+		CreatedBy:    []string{"CreateNetworkInterface", "RunInstances"},
+		ArnTypes:     []string{"ec2:network-interface"},
+		TrailIdKeys:  []string{"networkInterfaceId"},
+		NukeType:     "EC2NetworkInterface",
+		Exists:       ec2NetworkInterfaceExists,
+		ExistsBatch:  ec2NetworkInterfaceExistsBatch,
+		BatchSize:    ec2BatchSize,
+		Describe:     ec2NetworkInterfaceDescribe,
+		Delete:       ec2NetworkInterfaceDelete,
+		DeleteScript: ec2NetworkInterfaceDeleteScript,
+		Tag:          ec2Tag,
+		DeleteAfter: []string{
+			"AWS::EC2::Instance",
+			"AWS::EC2::NatGateway",
+			"AWS::ElasticLoadBalancing::LoadBalancer",
+			"AWS::ElasticLoadBalancingV2::LoadBalancer",
+		},
+	})
+}
+
+func ec2NetworkInterfaceExists(resource *Resource) (bool, error) {
+	found, errs := ec2NetworkInterfaceExistsBatch(resource.Region, []string{resource.Name})
+	return found[resource.Name], errs[resource.Name]
+}
+
+func ec2NetworkInterfaceExistsBatch(region string, networkInterfaceIds []string) (map[string]bool, map[string]error) {
+	v("exists?", networkInterfaceIds)
+	svc := ec2Client(region)
+	result := map[string]bool{}
+
+	input := &ec2.DescribeNetworkInterfacesInput{
+		Filters: ec2Filter("network-interface-id", networkInterfaceIds),
+	}
+	err := svc.DescribeNetworkInterfacesPages(input,
+		func(page *ec2.DescribeNetworkInterfacesOutput, lastPage bool) bool {
+			for _, networkInterface := range page.NetworkInterfaces {
+				result[*networkInterface.NetworkInterfaceId] = true
+			}
+			return true
+		})
+	if err != nil {
+		return nil, batchErrors(networkInterfaceIds, err)
+	}
+
+	return result, nil
+}
+
+func ec2NetworkInterfaceDescribe(resource *Resource) (*ResourceDetails, error) {
+	svc := ec2Client(resource.Region)
+
+	result, err := svc.DescribeNetworkInterfaces(&ec2.DescribeNetworkInterfacesInput{
+		NetworkInterfaceIds: []*string{&resource.Name},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	for _, networkInterface := range result.NetworkInterfaces {
+		return &ResourceDetails{
+			Owner: aws.StringValue(networkInterface.OwnerId),
+			Tags:  ec2Tags(networkInterface.TagSet),
+		}, nil
+	}
+
+	return nil, nil
+}
+
+func ec2NetworkInterfaceDelete(resource *Resource) error {
+	svc := ec2Client(resource.Region)
+
+	result, err := svc.DescribeNetworkInterfaces(&ec2.DescribeNetworkInterfacesInput{
+		NetworkInterfaceIds: []*string{&resource.Name},
+	})
+	if err != nil {
+		return err
+	}
+
+	for _, networkInterface := range result.NetworkInterfaces {
+		if networkInterface.Attachment != nil &&
+			networkInterface.Attachment.AttachmentId != nil {
+			_, err = svc.DetachNetworkInterface(&ec2.DetachNetworkInterfaceInput{
+				AttachmentId: networkInterface.Attachment.AttachmentId,
+				Force:        aws.Bool(true),
+			})
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	_, err = svc.DeleteNetworkInterface(&ec2.DeleteNetworkInterfaceInput{
+		NetworkInterfaceId: &resource.Name,
+	})
+	return err
+}
+
+func ec2NetworkInterfaceDeleteScript(resource *Resource, aws string) []string {
+	id := shellQuote(resource.Name)
+	return []string{
+		"attachment=$(" + aws + " ec2 describe-network-interfaces --network-interface-ids " + id + " --query 'NetworkInterfaces[0].Attachment.AttachmentId' --output text)",
+		`if [ "$attachment" != None ]; then ` + aws + ` ec2 detach-network-interface --attachment-id "$attachment" --force; fi`,
+		aws + " ec2 delete-network-interface --network-interface-id " + id,
+	}
+}
@@ -0,0 +1,143 @@
+package main
+
+import (
+	"errors"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/ec2"
+)
+
+func init() {
+	registerResourceType("AWS::EC2::RouteTable", &resourceHandler{
+		CreatedBy:    []string{"CreateRouteTable"},
+		ArnTypes:     []string{"ec2:route-table"},
+		TrailIdKeys:  []string{"routeTableId"},
+		NukeType:     "EC2RouteTable",
+		Exists:       ec2RouteTableExists,
+		ExistsBatch:  ec2RouteTableExistsBatch,
+		BatchSize:    ec2BatchSize,
+		Describe:     ec2RouteTableDescribe,
+		Keep:         ec2RouteTableKeep,
+		Delete:       ec2RouteTableDelete,
+		DeleteScript: ec2RouteTableDeleteScript,
+		Tag:          ec2Tag,
+		DeleteAfter: []string{
+			"AWS::EC2::NatGateway",
+			"AWS::EC2::SubnetRouteTableAssociation",
+		},
+	})
+}
+
+func ec2RouteTableExists(resource *Resource) (bool, error) {
+	found, errs := ec2RouteTableExistsBatch(resource.Region, []string{resource.Name})
+	return found[resource.Name], errs[resource.Name]
+}
+
+func ec2RouteTableExistsBatch(region string, routeTableIds []string) (map[string]bool, map[string]error) {
+	v("exists?", routeTableIds)
+	svc := ec2Client(region)
+	result := map[string]bool{}
+
+	input := &ec2.DescribeRouteTablesInput{
+		Filters: ec2Filter("route-table-id", routeTableIds),
+	}
+	err := svc.DescribeRouteTablesPages(input,
+		func(page *ec2.DescribeRouteTablesOutput, lastPage bool) bool {
+			for _, routeTable := range page.RouteTables {
+				result[*routeTable.RouteTableId] = true
+			}
+			return true
+		})
+	if err != nil {
+		return nil, batchErrors(routeTableIds, err)
+	}
+
+	return result, nil
+}
+
+func ec2RouteTableDescribe(resource *Resource) (*ResourceDetails, error) {
+	svc := ec2Client(resource.Region)
+
+	result, err := svc.DescribeRouteTables(&ec2.DescribeRouteTablesInput{
+		RouteTableIds: []*string{&resource.Name},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	for _, routeTable := range result.RouteTables {
+		return &ResourceDetails{
+			Owner: aws.StringValue(routeTable.OwnerId),
+			Tags:  ec2Tags(routeTable.Tags),
+		}, nil
+	}
+
+	return nil, nil
+}
+
+// errMainRouteTable is the error of deleting a main route table, which goes
+// away with its VPC.
+var errMainRouteTable = errors.New("main route table, deleted with its VPC")
+
+// ec2RouteTableKeep keeps the main route table of a VPC, it cannot be
+// deleted.
+func ec2RouteTableKeep(resource *Resource) (string, error) {
+	result, err := ec2Client(resource.Region).DescribeRouteTables(&ec2.DescribeRouteTablesInput{
+		RouteTableIds: []*string{&resource.Name},
+	})
+	if err != nil {
+		return "", err
+	}
+
+	for _, routeTable := range result.RouteTables {
+		for _, association := range routeTable.Associations {
+			if aws.BoolValue(association.Main) {
+				return "main route table of " + aws.StringValue(routeTable.VpcId), nil
+			}
+		}
+	}
+	return "", nil
+}
+
+func ec2RouteTableDelete(resource *Resource) error {
+	svc := ec2Client(resource.Region)
+
+	result, err := svc.DescribeRouteTables(&ec2.DescribeRouteTablesInput{
+		RouteTableIds: []*string{&resource.Name},
+	})
+	if err != nil {
+		return err
+	}
+
+	for _, routeTable := range result.RouteTables {
+		for _, association := range routeTable.Associations {
+			if aws.BoolValue(association.Main) {
+				return errMainRouteTable
+			}
+			_, err = svc.DisassociateRouteTable(&ec2.DisassociateRouteTableInput{
+				AssociationId: association.RouteTableAssociationId,
+			})
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	_, err = svc.DeleteRouteTable(&ec2.DeleteRouteTableInput{
+		RouteTableId: &resource.Name,
+	})
+	return err
+}
+
+// The main route table goes away with its VPC, it is skipped.
+func ec2RouteTableDeleteScript(resource *Resource, aws string) []string {
+	id := shellQuote(resource.Name)
+	describe := aws + " ec2 describe-route-tables --route-table-ids " + id + " --output text --query "
+	return []string{
+		"if [ \"$(" + describe + "'length(RouteTables[0].Associations[?Main])')\" = 0 ]; then",
+		"  for association in $(" + describe + "'RouteTables[0].Associations[].RouteTableAssociationId'); do",
+		"    " + aws + ` ec2 disassociate-route-table --association-id "$association"`,
+		"  done",
+		"  " + aws + " ec2 delete-route-table --route-table-id " + id,
+		"fi",
+	}
+}
@@ -0,0 +1,272 @@
+package main
+
+import (
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/service/ec2"
+	"sort"
+	"strings"
+)
+
+func init() {
+	registerResourceType("AWS::EC2::SecurityGroup", &resourceHandler{
+		CreatedBy:    []string{"CreateSecurityGroup"},
+		ArnTypes:     []string{"ec2:security-group"},
+		TrailIdKeys:  []string{"groupId"},
+		NukeType:     "EC2SecurityGroup",
+		Exists:       ec2SecurityGroupExists,
+		ExistsBatch:  ec2SecurityGroupExistsBatch,
+		BatchSize:    ec2BatchSize,
+		Describe:     ec2SecurityGroupDescribe,
+		Delete:       ec2SecurityGroupDelete,
+		DeleteScript: ec2SecurityGroupDeleteScript,
+		Tag:          ec2Tag,
+		Quarantine:   ec2SecurityGroupRevokeIngress,
+		DeleteAfter: []string{
+			"AWS::EC2::Instance",
+			"AWS::EC2::NetworkInterface",
+			"AWS::ElasticLoadBalancing::LoadBalancer",
+			"AWS::ElasticLoadBalancingV2::LoadBalancer",
+			"AWS::EC2::VPCEndpoint",
+		},
+	})
+}
+
+func ec2SecurityGroupExists(resource *Resource) (bool, error) {
+	found, errs := ec2SecurityGroupExistsBatch(resource.Region, []string{resource.Name})
+	return found[resource.Name], errs[resource.Name]
+}
+
+func ec2SecurityGroupExistsBatch(region string, securityGroupIds []string) (map[string]bool, map[string]error) {
+	v("exists?", securityGroupIds)
+	svc := ec2Client(region)
+	result := map[string]bool{}
+	var defaultVpcErr error
+
+	input := &ec2.DescribeSecurityGroupsInput{
+		Filters: ec2Filter("group-id", securityGroupIds),
+	}
+	err := svc.DescribeSecurityGroupsPages(input,
+		func(page *ec2.DescribeSecurityGroupsOutput, lastPage bool) bool {
+			for _, group := range page.SecurityGroups {
+				// skip securityGroup of the default VPC
+				isDefault, err := isDefaultVpc(region, aws.StringValue(group.VpcId))
+				if err != nil {
+					defaultVpcErr = err
+					return false
+				}
+				if !isDefault {
+					result[*group.GroupId] = true
+				}
+			}
+			return true
+		})
+	if err == nil {
+		err = defaultVpcErr
+	}
+	if err != nil {
+		return nil, batchErrors(securityGroupIds, err)
+	}
+
+	return result, nil
+}
+
+func ec2SecurityGroupDescribe(resource *Resource) (*ResourceDetails, error) {
+	svc := ec2Client(resource.Region)
+
+	result, err := svc.DescribeSecurityGroups(&ec2.DescribeSecurityGroupsInput{
+		GroupIds: []*string{&resource.Name},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	for _, group := range result.SecurityGroups {
+		return &ResourceDetails{
+			Owner: aws.StringValue(group.OwnerId),
+			Tags:  ec2Tags(group.Tags),
+		}, nil
+	}
+
+	return nil, nil
+}
+
+// ec2SecurityGroupRevokeIngress revokes the ingress rules of the group, it
+// quarantines the group.
+func ec2SecurityGroupRevokeIngress(resource *Resource) error {
+	svc := ec2Client(resource.Region)
+
+	result, err := svc.DescribeSecurityGroups(&ec2.DescribeSecurityGroupsInput{
+		GroupIds: []*string{&resource.Name},
+	})
+	if err != nil {
+		return err
+	}
+
+	for _, group := range result.SecurityGroups {
+		if len(group.IpPermissions) > 0 {
+			_, err = svc.RevokeSecurityGroupIngress(&ec2.RevokeSecurityGroupIngressInput{
+				GroupId:       group.GroupId,
+				IpPermissions: group.IpPermissions,
+			})
+			if err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// ec2SecurityGroupsInUse returns true if network interfaces, ex: of
+// instances or load balancers, still use one of the groups.
+func ec2SecurityGroupsInUse(region string, groupIds []string) (bool, error) {
+	result, err := ec2Client(region).DescribeNetworkInterfaces(&ec2.DescribeNetworkInterfacesInput{
+		Filters: ec2Filter("group-id", groupIds),
+	})
+	if err != nil {
+		return false, err
+	}
+	return len(result.NetworkInterfaces) > 0, nil
+}
+
+// ec2SecurityGroupReferencing returns the other security groups being
+// deleted in the region of the group, which may reference it in their rules.
+func ec2SecurityGroupReferencing(resource *Resource) []string {
+	groupIds := []string{}
+	for _, other := range deleteSet {
+		if other.Type == resource.Type && other.Region == resource.Region && other.Name != resource.Name {
+			groupIds = append(groupIds, other.Name)
+		}
+	}
+	sort.Strings(groupIds)
+	return groupIds
+}
+
+// ec2SecurityGroupRevokeReferences revokes the rules referencing the group
+// in the other groups being deleted, unless they are still in use. It
+// returns false when no rule was revoked.
+func ec2SecurityGroupRevokeReferences(resource *Resource) (bool, error) {
+	svc := ec2Client(resource.Region)
+	revoked := false
+
+	for _, groupId := range ec2SecurityGroupReferencing(resource) {
+		inUse, err := ec2SecurityGroupsInUse(resource.Region, []string{groupId})
+		if err != nil {
+			return revoked, err
+		}
+		if inUse {
+			continue
+		}
+
+		ingress := []*string{}
+		egress := []*string{}
+		err = svc.DescribeSecurityGroupRulesPages(&ec2.DescribeSecurityGroupRulesInput{
+			Filters: ec2Filter("group-id", []string{groupId}),
+		}, func(page *ec2.DescribeSecurityGroupRulesOutput, lastPage bool) bool {
+			for _, rule := range page.SecurityGroupRules {
+				if rule.ReferencedGroupInfo == nil || aws.StringValue(rule.ReferencedGroupInfo.GroupId) != resource.Name {
+					continue
+				}
+				if aws.BoolValue(rule.IsEgress) {
+					egress = append(egress, rule.SecurityGroupRuleId)
+				} else {
+					ingress = append(ingress, rule.SecurityGroupRuleId)
+				}
+			}
+			return true
+		})
+		if err != nil {
+			return revoked, err
+		}
+
+		if len(ingress) > 0 {
+			v("revoking ingress rules of", groupId, "referencing", resource.Name)
+			if _, err := svc.RevokeSecurityGroupIngress(&ec2.RevokeSecurityGroupIngressInput{
+				GroupId:              aws.String(groupId),
+				SecurityGroupRuleIds: ingress,
+			}); err != nil {
+				return revoked, err
+			}
+			revoked = true
+		}
+		if len(egress) > 0 {
+			v("revoking egress rules of", groupId, "referencing", resource.Name)
+			if _, err := svc.RevokeSecurityGroupEgress(&ec2.RevokeSecurityGroupEgressInput{
+				GroupId:              aws.String(groupId),
+				SecurityGroupRuleIds: egress,
+			}); err != nil {
+				return revoked, err
+			}
+			revoked = true
+		}
+	}
+	return revoked, nil
+}
+
+// ec2SecurityGroupDelete deletes the group. When other groups reference it,
+// ex: groups allowing each other, the rules referencing it are revoked from
+// the other groups being deleted. Rules are never revoked while network
+// interfaces use the group, the delete is retried once they are gone.
+func ec2SecurityGroupDelete(resource *Resource) error {
+	svc := ec2Client(resource.Region)
+	input := &ec2.DeleteSecurityGroupInput{
+		GroupId: &resource.Name,
+	}
+
+	_, err := svc.DeleteSecurityGroup(input)
+	if aerr, ok := err.(awserr.Error); !ok || aerr.Code() != "DependencyViolation" {
+		return err
+	}
+
+	inUse, inUseErr := ec2SecurityGroupsInUse(resource.Region, []string{resource.Name})
+	if inUseErr != nil || inUse {
+		return err
+	}
+	revoked, revokeErr := ec2SecurityGroupRevokeReferences(resource)
+	if revokeErr != nil {
+		return revokeErr
+	}
+	if !revoked {
+		return err
+	}
+
+	_, err = svc.DeleteSecurityGroup(input)
+	return err
+}
+
+// ec2SecurityGroupDeleteScript does what ec2SecurityGroupDelete does, the
+// in use and rule checks run with the script.
+func ec2SecurityGroupDeleteScript(resource *Resource, aws string) []string {
+	id := shellQuote(resource.Name)
+	inUse := func(group string) string {
+		return `[ "$(` + aws + " ec2 describe-network-interfaces --filters Name=group-id,Values=" + group +
+			` --query 'length(NetworkInterfaces)' --output text)" != 0 ]`
+	}
+
+	lines := []string{
+		"if ! " + aws + " ec2 delete-security-group --group-id " + id + "; then",
+		"  if " + inUse(id) + "; then echo " + shellQuote(resource.Name+" is in use") + " >&2; exit 1; fi",
+	}
+	if groups := ec2SecurityGroupReferencing(resource); len(groups) > 0 {
+		quoted := []string{}
+		for _, group := range groups {
+			quoted = append(quoted, shellQuote(group))
+		}
+		lines = append(lines, "  for group in "+strings.Join(quoted, " ")+"; do",
+			"    if "+inUse(`"$group"`)+"; then continue; fi")
+		for _, direction := range []string{"ingress", "egress"} {
+			egress := "!IsEgress"
+			if direction == "egress" {
+				egress = "IsEgress"
+			}
+			lines = append(lines,
+				"    rules=$("+aws+` ec2 describe-security-group-rules --filters Name=group-id,Values="$group"`+
+					` --query "SecurityGroupRules[?ReferencedGroupInfo.GroupId=='`+resource.Name+`' && `+egress+`].SecurityGroupRuleId" --output text)`,
+				`    if [ -n "$rules" ]; then `+aws+" ec2 revoke-security-group-"+direction+` --group-id "$group" --security-group-rule-ids $rules; fi`)
+		}
+		lines = append(lines, "  done")
+	}
+	return append(lines,
+		"  "+aws+" ec2 delete-security-group --group-id "+id,
+		"fi")
+}
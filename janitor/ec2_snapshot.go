@@ -0,0 +1,86 @@
+package main
+
+import (
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/ec2"
+)
+
+func init() {
+	registerResourceType("AWS::EC2::Snapshot", &resourceHandler{
+		CreatedBy:    []string{"CreateSnapshot", "CreateSnapshots", "CopySnapshot", "ImportSnapshot"},
+		ArnTypes:     []string{"ec2:snapshot"},
+		TrailIdKeys:  []string{"snapshotId"},
+		NukeType:     "EC2Snapshot",
+		Exists:       ec2SnapshotExists,
+		ExistsBatch:  ec2SnapshotExistsBatch,
+		BatchSize:    ec2BatchSize,
+		Describe:     ec2SnapshotDescribe,
+		Delete:       ec2SnapshotDelete,
+		DeleteScript: scriptCommand("ec2 delete-snapshot --snapshot-id"),
+		Tag:          ec2Tag,
+		DeleteAfter: []string{
+			// A snapshot cannot be deleted while an AMI uses it
+			"AWS::EC2::Ami",
+		},
+	})
+}
+
+func ec2SnapshotExists(resource *Resource) (bool, error) {
+	found, errs := ec2SnapshotExistsBatch(resource.Region, []string{resource.Name})
+	return found[resource.Name], errs[resource.Name]
+}
+
+func ec2SnapshotExistsBatch(region string, snapshotIds []string) (map[string]bool, map[string]error) {
+	v("exists?", snapshotIds)
+	svc := ec2Client(region)
+	result := map[string]bool{}
+
+	// Only our own snapshots, public snapshots are not ours to delete
+	input := &ec2.DescribeSnapshotsInput{
+		Filters:  ec2Filter("snapshot-id", snapshotIds),
+		OwnerIds: []*string{aws.String("self")},
+	}
+	err := svc.DescribeSnapshotsPages(input,
+		func(page *ec2.DescribeSnapshotsOutput, lastPage bool) bool {
+			for _, snapshot := range page.Snapshots {
+				result[*snapshot.SnapshotId] = true
+			}
+			return true
+		})
+	if err != nil {
+		return nil, batchErrors(snapshotIds, err)
+	}
+
+	return result, nil
+}
+
+func ec2SnapshotDescribe(resource *Resource) (*ResourceDetails, error) {
+	svc := ec2Client(resource.Region)
+
+	result, err := svc.DescribeSnapshots(&ec2.DescribeSnapshotsInput{
+		SnapshotIds: []*string{&resource.Name},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	for _, snapshot := range result.Snapshots {
+		return &ResourceDetails{
+			Owner:        aws.StringValue(snapshot.OwnerId),
+			Tags:         ec2Tags(snapshot.Tags),
+			CreationTime: snapshot.StartTime,
+			VolumeSize:   aws.Int64Value(snapshot.VolumeSize),
+		}, nil
+	}
+
+	return nil, nil
+}
+
+func ec2SnapshotDelete(resource *Resource) error {
+	svc := ec2Client(resource.Region)
+
+	_, err := svc.DeleteSnapshot(&ec2.DeleteSnapshotInput{
+		SnapshotId: &resource.Name,
+	})
+	return err
+}
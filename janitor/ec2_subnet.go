@@ -0,0 +1,95 @@
+package main
+
+import (
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/ec2"
+)
+
+func init() {
+	registerResourceType("AWS::EC2::Subnet", &resourceHandler{
+		CreatedBy:    []string{"CreateSubnet", "CreateDefaultSubnet"},
+		ArnTypes:     []string{"ec2:subnet"},
+		TrailIdKeys:  []string{"subnetId"},
+		NukeType:     "EC2Subnet",
+		Exists:       ec2SubnetExists,
+		ExistsBatch:  ec2SubnetExistsBatch,
+		BatchSize:    ec2BatchSize,
+		Describe:     ec2SubnetDescribe,
+		Delete:       ec2SubnetDelete,
+		DeleteScript: scriptCommand("ec2 delete-subnet --subnet-id"),
+		Tag:          ec2Tag,
+		DeleteAfter: []string{
+			"AWS::EC2::Instance",
+			"AWS::EC2::NetworkInterface",
+			"AWS::EC2::NatGateway",
+			"AWS::EC2::EIP",
+			"AWS::ElasticLoadBalancing::LoadBalancer",
+			"AWS::ElasticLoadBalancingV2::LoadBalancer",
+			"AWS::EC2::VPCEndpoint",
+		},
+	})
+}
+
+func ec2SubnetExists(resource *Resource) (bool, error) {
+	found, errs := ec2SubnetExistsBatch(resource.Region, []string{resource.Name})
+	return found[resource.Name], errs[resource.Name]
+}
+
+func ec2SubnetExistsBatch(region string, subnetIds []string) (map[string]bool, map[string]error) {
+	v("exists?", subnetIds)
+	svc := ec2Client(region)
+	result := map[string]bool{}
+
+	input := &ec2.DescribeSubnetsInput{
+		Filters: ec2Filter("subnet-id", subnetIds),
+	}
+	err := svc.DescribeSubnetsPages(input,
+		func(page *ec2.DescribeSubnetsOutput, lastPage bool) bool {
+			for _, subnet := range page.Subnets {
+				// exclude default subnet
+				if *subnet.DefaultForAz {
+					continue
+				}
+				switch *subnet.State {
+				case "deleted", "deleting":
+				default:
+					result[*subnet.SubnetId] = true
+				}
+			}
+			return true
+		})
+	if err != nil {
+		return nil, batchErrors(subnetIds, err)
+	}
+
+	return result, nil
+}
+
+func ec2SubnetDescribe(resource *Resource) (*ResourceDetails, error) {
+	svc := ec2Client(resource.Region)
+
+	result, err := svc.DescribeSubnets(&ec2.DescribeSubnetsInput{
+		SubnetIds: []*string{&resource.Name},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	for _, subnet := range result.Subnets {
+		return &ResourceDetails{
+			Owner: aws.StringValue(subnet.OwnerId),
+			Tags:  ec2Tags(subnet.Tags),
+		}, nil
+	}
+
+	return nil, nil
+}
+
+func ec2SubnetDelete(resource *Resource) error {
+	svc := ec2Client(resource.Region)
+
+	_, err := svc.DeleteSubnet(&ec2.DeleteSubnetInput{
+		SubnetId: &resource.Name,
+	})
+	return err
+}
@@ -0,0 +1,81 @@
+package main
+
+import (
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/ec2"
+)
+
+func init() {
+	registerResourceType("AWS::EC2::SubnetRouteTableAssociation", &resourceHandler{
+		CreatedBy:      []string{"AssociateRouteTable"},
+		Exists:         ec2SubnetRouteTableAssociationExists,
+		ExistsBatch:    ec2SubnetRouteTableAssociationExistsBatch,
+		BatchSize:      ec2BatchSize,
+		Delete:         ec2SubnetRouteTableAssociationDelete,
+		DeleteScript:   scriptCommand("ec2 disassociate-route-table --association-id"),
+		QuarantineWith: ec2SubnetRouteTableAssociationQuarantineWith,
+	})
+}
+
+func ec2SubnetRouteTableAssociationExists(resource *Resource) (bool, error) {
+	found, errs := ec2SubnetRouteTableAssociationExistsBatch(resource.Region, []string{resource.Name})
+	return found[resource.Name], errs[resource.Name]
+}
+
+func ec2SubnetRouteTableAssociationExistsBatch(region string, associationIds []string) (map[string]bool, map[string]error) {
+	v("exists?", associationIds)
+	svc := ec2Client(region)
+	result := map[string]bool{}
+
+	input := &ec2.DescribeRouteTablesInput{
+		Filters: ec2Filter("association.route-table-association-id", associationIds),
+	}
+	err := svc.DescribeRouteTablesPages(input,
+		func(page *ec2.DescribeRouteTablesOutput, lastPage bool) bool {
+			for _, routeTable := range page.RouteTables {
+				for _, association := range routeTable.Associations {
+					result[*association.RouteTableAssociationId] = true
+				}
+			}
+			return true
+		})
+	if err != nil {
+		return nil, batchErrors(associationIds, err)
+	}
+
+	return result, nil
+}
+
+// ec2SubnetRouteTableAssociationQuarantineWith returns the route table and
+// the subnet of the association, it is quarantined with either.
+func ec2SubnetRouteTableAssociationQuarantineWith(resource *Resource) ([][2]string, error) {
+	result, err := ec2Client(resource.Region).DescribeRouteTables(&ec2.DescribeRouteTablesInput{
+		Filters: ec2Filter("association.route-table-association-id", []string{resource.Name}),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	names := [][2]string{}
+	for _, routeTable := range result.RouteTables {
+		for _, association := range routeTable.Associations {
+			if aws.StringValue(association.RouteTableAssociationId) != resource.Name {
+				continue
+			}
+			names = append(names, [2]string{"AWS::EC2::RouteTable", aws.StringValue(routeTable.RouteTableId)})
+			if association.SubnetId != nil {
+				names = append(names, [2]string{"AWS::EC2::Subnet", *association.SubnetId})
+			}
+		}
+	}
+	return names, nil
+}
+
+func ec2SubnetRouteTableAssociationDelete(resource *Resource) error {
+	svc := ec2Client(resource.Region)
+
+	_, err := svc.DisassociateRouteTable(&ec2.DisassociateRouteTableInput{
+		AssociationId: &resource.Name,
+	})
+	return err
+}
@@ -0,0 +1,87 @@
+package main
+
+import (
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/ec2"
+)
+
+func init() {
+	registerResourceType("AWS::EC2::Volume", &resourceHandler{
+		CreatedBy:    []string{"CreateVolume"},
+		ArnTypes:     []string{"ec2:volume"},
+		TrailIdKeys:  []string{"volumeId"},
+		NukeType:     "EC2Volume",
+		Exists:       ec2VolumeExists,
+		ExistsBatch:  ec2VolumeExistsBatch,
+		BatchSize:    ec2BatchSize,
+		Describe:     ec2VolumeDescribe,
+		Delete:       ec2VolumeDelete,
+		DeleteScript: scriptCommand("ec2 delete-volume --volume-id"),
+		Tag:          ec2Tag,
+		DeleteAfter: []string{
+			"AWS::EC2::Instance",
+		},
+	})
+}
+
+func ec2VolumeExists(resource *Resource) (bool, error) {
+	found, errs := ec2VolumeExistsBatch(resource.Region, []string{resource.Name})
+	return found[resource.Name], errs[resource.Name]
+}
+
+func ec2VolumeExistsBatch(region string, volumeIds []string) (map[string]bool, map[string]error) {
+	v("exists?", volumeIds)
+	svc := ec2Client(region)
+	result := map[string]bool{}
+
+	input := &ec2.DescribeVolumesInput{
+		Filters: ec2Filter("volume-id", volumeIds),
+	}
+	err := svc.DescribeVolumesPages(input,
+		func(page *ec2.DescribeVolumesOutput, lastPage bool) bool {
+			for _, volume := range page.Volumes {
+				switch *volume.State {
+				case "deleted", "deleting":
+				default:
+					result[*volume.VolumeId] = true
+				}
+			}
+			return true
+		})
+	if err != nil {
+		return nil, batchErrors(volumeIds, err)
+	}
+
+	return result, nil
+}
+
+func ec2VolumeDescribe(resource *Resource) (*ResourceDetails, error) {
+	svc := ec2Client(resource.Region)
+
+	result, err := svc.DescribeVolumes(&ec2.DescribeVolumesInput{
+		VolumeIds: []*string{&resource.Name},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	for _, volume := range result.Volumes {
+		return &ResourceDetails{
+			Tags:         ec2Tags(volume.Tags),
+			CreationTime: volume.CreateTime,
+			VolumeType:   aws.StringValue(volume.VolumeType),
+			VolumeSize:   aws.Int64Value(volume.Size),
+		}, nil
+	}
+
+	return nil, nil
+}
+
+func ec2VolumeDelete(resource *Resource) error {
+	svc := ec2Client(resource.Region)
+
+	_, err := svc.DeleteVolume(&ec2.DeleteVolumeInput{
+		VolumeId: &resource.Name,
+	})
+	return err
+}
@@ -0,0 +1,123 @@
+package main
+
+import (
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/ec2"
+	"sync"
+)
+
+func init() {
+	registerResourceType("AWS::EC2::VPC", &resourceHandler{
+		CreatedBy:    []string{"CreateVpc", "CreateDefaultVpc"},
+		ArnTypes:     []string{"ec2:vpc"},
+		TrailIdKeys:  []string{"vpcId"},
+		NukeType:     "EC2VPC",
+		Exists:       ec2VpcExists,
+		ExistsBatch:  ec2VpcExistsBatch,
+		BatchSize:    ec2BatchSize,
+		Describe:     ec2VpcDescribe,
+		Delete:       ec2VpcDelete,
+		DeleteScript: scriptCommand("ec2 delete-vpc --vpc-id"),
+		Tag:          ec2Tag,
+		DeleteAfter: []string{
+			"AWS::EC2::Subnet",
+			"AWS::EC2::RouteTable",
+			"AWS::EC2::InternetGateway",
+			"AWS::EC2::SecurityGroup",
+			"AWS::EC2::NetworkInterface",
+			"AWS::EC2::VPCEndpoint",
+			"AWS::EC2::NetworkAcl",
+		},
+	})
+}
+
+func ec2VpcExists(resource *Resource) (bool, error) {
+	found, errs := ec2VpcExistsBatch(resource.Region, []string{resource.Name})
+	return found[resource.Name], errs[resource.Name]
+}
+
+func ec2VpcExistsBatch(region string, vpcIds []string) (map[string]bool, map[string]error) {
+	v("exists?", vpcIds)
+	svc := ec2Client(region)
+	result := map[string]bool{}
+
+	input := &ec2.DescribeVpcsInput{
+		Filters: ec2Filter("vpc-id", vpcIds),
+	}
+	err := svc.DescribeVpcsPages(input,
+		func(page *ec2.DescribeVpcsOutput, lastPage bool) bool {
+			for _, vpc := range page.Vpcs {
+				// filter out default VPC
+				if *vpc.IsDefault {
+					continue
+				}
+				switch *vpc.State {
+				case "deleted", "deleting":
+				default:
+					result[*vpc.VpcId] = true
+				}
+			}
+			return true
+		})
+	if err != nil {
+		return nil, batchErrors(vpcIds, err)
+	}
+
+	return result, nil
+}
+
+func ec2VpcDescribe(resource *Resource) (*ResourceDetails, error) {
+	svc := ec2Client(resource.Region)
+
+	result, err := svc.DescribeVpcs(&ec2.DescribeVpcsInput{
+		VpcIds: []*string{&resource.Name},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	for _, vpc := range result.Vpcs {
+		return &ResourceDetails{
+			Owner: aws.StringValue(vpc.OwnerId),
+			Tags:  ec2Tags(vpc.Tags),
+		}, nil
+	}
+
+	return nil, nil
+}
+
+func ec2VpcDelete(resource *Resource) error {
+	svc := ec2Client(resource.Region)
+
+	_, err := svc.DeleteVpc(&ec2.DeleteVpcInput{
+		VpcId: &resource.Name,
+	})
+	return err
+}
+
+var defaultVpcs = map[string]map[string]bool{}
+var defaultVpcsMutex sync.Mutex
+
+// isDefaultVpc returns true if vpcId is the default VPC of the region.
+// The default VPCs are looked up once per region.
+func isDefaultVpc(region, vpcId string) (bool, error) {
+	defaultVpcsMutex.Lock()
+	defer defaultVpcsMutex.Unlock()
+
+	if defaultVpcs[region] == nil {
+		svc := ec2Client(region)
+		result, err := svc.DescribeVpcs(&ec2.DescribeVpcsInput{
+			Filters: ec2Filter("isDefault", []string{"true"}),
+		})
+		if err != nil {
+			return false, err
+		}
+
+		defaultVpcs[region] = map[string]bool{}
+		for _, vpc := range result.Vpcs {
+			defaultVpcs[region][*vpc.VpcId] = true
+		}
+	}
+
+	return defaultVpcs[region][vpcId], nil
+}
@@ -0,0 +1,99 @@
+package main
+
+import (
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/service/ec2"
+	"strings"
+)
+
+func init() {
+	registerResourceType("AWS::EC2::VPCEndpoint", &resourceHandler{
+		CreatedBy:    []string{"CreateVpcEndpoint"},
+		ArnTypes:     []string{"ec2:vpc-endpoint"},
+		TrailIdKeys:  []string{"vpcEndpointId"},
+		NukeType:     "EC2VPCEndpoint",
+		Exists:       ec2VpcEndpointExists,
+		ExistsBatch:  ec2VpcEndpointExistsBatch,
+		BatchSize:    ec2BatchSize,
+		Describe:     ec2VpcEndpointDescribe,
+		Delete:       ec2VpcEndpointDelete,
+		DeleteScript: scriptCommand("ec2 delete-vpc-endpoints --vpc-endpoint-ids"),
+		Tag:          ec2Tag,
+	})
+}
+
+func ec2VpcEndpointExists(resource *Resource) (bool, error) {
+	found, errs := ec2VpcEndpointExistsBatch(resource.Region, []string{resource.Name})
+	return found[resource.Name], errs[resource.Name]
+}
+
+func ec2VpcEndpointExistsBatch(region string, vpcEndpointIds []string) (map[string]bool, map[string]error) {
+	v("exists?", vpcEndpointIds)
+	svc := ec2Client(region)
+	result := map[string]bool{}
+
+	input := &ec2.DescribeVpcEndpointsInput{
+		Filters: ec2Filter("vpc-endpoint-id", vpcEndpointIds),
+	}
+	err := svc.DescribeVpcEndpointsPages(input,
+		func(page *ec2.DescribeVpcEndpointsOutput, lastPage bool) bool {
+			for _, vpcEndpoint := range page.VpcEndpoints {
+				switch strings.ToLower(*vpcEndpoint.State) {
+				case "deleted", "deleting":
+				default:
+					result[*vpcEndpoint.VpcEndpointId] = true
+				}
+			}
+			return true
+		})
+	if err != nil {
+		return nil, batchErrors(vpcEndpointIds, err)
+	}
+
+	return result, nil
+}
+
+func ec2VpcEndpointDescribe(resource *Resource) (*ResourceDetails, error) {
+	svc := ec2Client(resource.Region)
+
+	result, err := svc.DescribeVpcEndpoints(&ec2.DescribeVpcEndpointsInput{
+		VpcEndpointIds: []*string{&resource.Name},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	for _, vpcEndpoint := range result.VpcEndpoints {
+		return &ResourceDetails{
+			Owner:        aws.StringValue(vpcEndpoint.OwnerId),
+			Tags:         ec2Tags(vpcEndpoint.Tags),
+			CreationTime: vpcEndpoint.CreationTimestamp,
+		}, nil
+	}
+
+	return nil, nil
+}
+
+func ec2VpcEndpointDelete(resource *Resource) error {
+	svc := ec2Client(resource.Region)
+
+	result, err := svc.DeleteVpcEndpoints(&ec2.DeleteVpcEndpointsInput{
+		VpcEndpointIds: []*string{&resource.Name},
+	})
+	if err != nil {
+		return err
+	}
+
+	// Failures are not returned as an error
+	for _, unsuccessful := range result.Unsuccessful {
+		if unsuccessful.Error != nil {
+			return awserr.New(
+				aws.StringValue(unsuccessful.Error.Code),
+				aws.StringValue(unsuccessful.Error.Message),
+				nil,
+			)
+		}
+	}
+	return nil
+}
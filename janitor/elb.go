@@ -2,154 +2,61 @@ package main
 
 import (
 	"github.com/aws/aws-sdk-go/aws"
-	"github.com/aws/aws-sdk-go/aws/awserr"
 	"github.com/aws/aws-sdk-go/service/elb"
 	"github.com/aws/aws-sdk-go/service/elbv2"
-	"strings"
 )
 
-var svcElb *elb.ELB
-var svcElbV2 *elbv2.ELBV2
+var svcElb = map[string]*elb.ELB{}
+var svcElbV2 = map[string]*elbv2.ELBV2{}
 
-func elasticLoadBalancingLoadBalancerExists(LoadBalancerId string) bool {
-	v("exists?", LoadBalancerId)
+func elbClient(region string) *elb.ELB {
+	clientsMutex.Lock()
+	defer clientsMutex.Unlock()
 
-	// Skip full ids, test only LoadBalancer names
-	if strings.Contains(LoadBalancerId, "arn:aws:") {
-		return false
-	}
-	if svcElb == nil {
-		svcElb = elb.New(sess)
-	}
-
-	input := &elb.DescribeLoadBalancersInput{
-		LoadBalancerNames: []*string{
-			&LoadBalancerId,
-		},
-	}
-	_, err := svcElb.DescribeLoadBalancers(input)
-	if err != nil {
-		if aerr, ok := err.(awserr.Error); ok {
-			switch aerr.Code() {
-			case "LoadBalancerNotFound":
-				return false
-			default:
-				logErr.Println(aerr.Code())
-				logErr.Println(aerr.Error())
-			}
-		} else {
-			logErr.Println(err.Error())
-		}
-		return false
-	} else {
-		return true
+	if svcElb[region] == nil {
+		svcElb[region] = elb.New(regionSession(region))
 	}
+	return svcElb[region]
 }
 
-func elasticLoadBalancingV2LoadBalancerExists(LoadBalancerId string) bool {
-	v("exists?", LoadBalancerId)
+func elbV2Client(region string) *elbv2.ELBV2 {
+	clientsMutex.Lock()
+	defer clientsMutex.Unlock()
 
-	// Skip full ids, test only LoadBalancer names
-	if !strings.Contains(LoadBalancerId, "arn:aws:") {
-		return false
-	}
-	if svcElb == nil {
-		svcElbV2 = elbv2.New(sess)
-	}
-
-	input := &elbv2.DescribeLoadBalancersInput{
-		LoadBalancerArns: []*string{
-			aws.String(LoadBalancerId),
-		},
-	}
-	_, err := svcElbV2.DescribeLoadBalancers(input)
-	if err != nil {
-		if aerr, ok := err.(awserr.Error); ok {
-			switch aerr.Code() {
-			case "LoadBalancerNotFound":
-				return false
-			default:
-				logErr.Println(aerr.Code())
-				logErr.Println(aerr.Error())
-				return false
-			}
-		} else {
-			logErr.Println(err.Error())
-			return false
-		}
-	} else {
-		return true
+	if svcElbV2[region] == nil {
+		svcElbV2[region] = elbv2.New(regionSession(region))
 	}
+	return svcElbV2[region]
 }
 
-func elasticLoadBalancingV2ListenerExists(ListenerId string) bool {
-	v("exists?", ListenerId)
-
-	// Skip full ids, test only Listener names
-	if !strings.Contains(ListenerId, "arn:aws:") {
-		return false
-	}
-	if svcElb == nil {
-		svcElbV2 = elbv2.New(sess)
-	}
-
-	input := &elbv2.DescribeListenersInput{
-		ListenerArns: []*string{
-			aws.String(ListenerId),
-		},
-	}
-	_, err := svcElbV2.DescribeListeners(input)
-	if err != nil {
-		if aerr, ok := err.(awserr.Error); ok {
-			switch aerr.Code() {
-			case "ListenerNotFound":
-				return false
-			default:
-				logErr.Println(aerr.Code())
-				logErr.Println(aerr.Error())
-				return false
-			}
-		} else {
-			logErr.Println(err.Error())
-			return false
-		}
-	} else {
-		return true
-	}
+// elbV2Tag is the Tag of the ELBv2 resources, by ARN.
+func elbV2Tag(resource *Resource, tags map[string]string) error {
+	list := []*elbv2.Tag{}
+	for key, value := range tags {
+		list = append(list, &elbv2.Tag{Key: aws.String(key), Value: aws.String(value)})
+	}
+	_, err := elbV2Client(resource.Region).AddTags(&elbv2.AddTagsInput{
+		ResourceArns: []*string{aws.String(resource.Name)},
+		Tags:         list,
+	})
+	return err
 }
 
-func elasticLoadBalancingV2TargetGroupExists(TargetGroupId string) bool {
-	v("exists?", TargetGroupId)
-
-	// Skip full ids, test only TargetGroup names
-	if !strings.Contains(TargetGroupId, "arn:aws:") {
-		return false
-	}
-	if svcElb == nil {
-		svcElbV2 = elbv2.New(sess)
+// elbV2Describe is the Describe of the ELBv2 resources without creation
+// time, by ARN: only their tags are known.
+func elbV2Describe(resource *Resource) (*ResourceDetails, error) {
+	result, err := elbV2Client(resource.Region).DescribeTags(&elbv2.DescribeTagsInput{
+		ResourceArns: []*string{aws.String(resource.Name)},
+	})
+	if err != nil {
+		return nil, err
 	}
 
-	input := &elbv2.DescribeTargetGroupsInput{
-		TargetGroupArns: []*string{
-			aws.String(TargetGroupId),
-		},
-	}
-	_, err := svcElbV2.DescribeTargetGroups(input)
-	if err != nil {
-		if aerr, ok := err.(awserr.Error); ok {
-			switch aerr.Code() {
-			case "TargetGroupNotFound":
-				return false
-			default:
-				logErr.Println(aerr.Code())
-				logErr.Println(aerr.Error())
-				return false
-			}
-		} else {
-			logErr.Println(err.Error())
-			return false
+	tags := map[string]string{}
+	for _, description := range result.TagDescriptions {
+		for _, tag := range description.Tags {
+			tags[aws.StringValue(tag.Key)] = aws.StringValue(tag.Value)
 		}
-	} else {
-		return true
 	}
+	return &ResourceDetails{Tags: tags}, nil
 }
@@ -0,0 +1,118 @@
+package main
+
+import (
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/elb"
+	"strings"
+)
+
+func init() {
+	registerResourceType("AWS::ElasticLoadBalancing::LoadBalancer", &resourceHandler{
+		CreatedBy:    []string{"CreateLoadBalancer"},
+		ArnTypes:     []string{"elasticloadbalancing:loadbalancer"},
+		TrailIdKeys:  []string{"loadBalancerName"},
+		NukeType:     "ELB",
+		Canonical:    elasticLoadBalancingLoadBalancerName,
+		Exists:       elasticLoadBalancingLoadBalancerExists,
+		ExistsBatch:  elasticLoadBalancingLoadBalancerExistsBatch,
+		BatchSize:    elbBatchSize,
+		Describe:     elasticLoadBalancingLoadBalancerDescribe,
+		Delete:       elasticLoadBalancingLoadBalancerDelete,
+		DeleteScript: scriptCommand("elb delete-load-balancer --load-balancer-name"),
+		Tag:          elasticLoadBalancingLoadBalancerTag,
+	})
+}
+
+// elasticLoadBalancingLoadBalancerName returns the name of a classic load
+// balancer ARN, arn:aws:elasticloadbalancing:region:account:loadbalancer/name.
+// Other ARNs are left as they are.
+func elasticLoadBalancingLoadBalancerName(region string, name string) string {
+	const prefix = ":loadbalancer/"
+	if i := strings.Index(name, prefix); isArn(name) && i >= 0 && !strings.Contains(name[i+len(prefix):], "/") {
+		return name[i+len(prefix):]
+	}
+	return name
+}
+
+func elasticLoadBalancingLoadBalancerExists(resource *Resource) (bool, error) {
+	found, errs := elasticLoadBalancingLoadBalancerExistsBatch(resource.Region, []string{resource.Name})
+	return found[resource.Name], errs[resource.Name]
+}
+
+func elasticLoadBalancingLoadBalancerExistsBatch(region string, LoadBalancerIds []string) (map[string]bool, map[string]error) {
+	v("exists?", LoadBalancerIds)
+
+	svc := elbClient(region)
+
+	// Only names can be looked up, ARNs are made names by Canonical
+	isName := func(name string) bool { return !isArn(name) }
+	return existsSupported(LoadBalancerIds, isName, "an ARN", func(names []string) (map[string]bool, map[string]error) {
+		return existsBisect(names, func(names []string) (map[string]bool, error) {
+			result := map[string]bool{}
+			output, err := svc.DescribeLoadBalancers(&elb.DescribeLoadBalancersInput{
+				LoadBalancerNames: aws.StringSlice(names),
+			})
+			if err != nil {
+				return nil, err
+			}
+			for _, loadBalancer := range output.LoadBalancerDescriptions {
+				result[*loadBalancer.LoadBalancerName] = true
+			}
+			return result, nil
+		})
+	})
+}
+
+func elasticLoadBalancingLoadBalancerDescribe(resource *Resource) (*ResourceDetails, error) {
+	svc := elbClient(resource.Region)
+
+	result, err := svc.DescribeLoadBalancers(&elb.DescribeLoadBalancersInput{
+		LoadBalancerNames: []*string{&resource.Name},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	for _, loadBalancer := range result.LoadBalancerDescriptions {
+		tagsResult, err := svc.DescribeTags(&elb.DescribeTagsInput{
+			LoadBalancerNames: []*string{&resource.Name},
+		})
+		if err != nil {
+			return nil, err
+		}
+		tags := map[string]string{}
+		for _, description := range tagsResult.TagDescriptions {
+			for _, tag := range description.Tags {
+				tags[aws.StringValue(tag.Key)] = aws.StringValue(tag.Value)
+			}
+		}
+
+		return &ResourceDetails{
+			Tags:         tags,
+			CreationTime: loadBalancer.CreatedTime,
+		}, nil
+	}
+
+	return nil, nil
+}
+
+func elasticLoadBalancingLoadBalancerDelete(resource *Resource) error {
+	svc := elbClient(resource.Region)
+
+	_, err := svc.DeleteLoadBalancer(&elb.DeleteLoadBalancerInput{
+		LoadBalancerName: aws.String(resource.Name),
+	})
+	return err
+}
+
+func elasticLoadBalancingLoadBalancerTag(resource *Resource, tags map[string]string) error {
+	list := []*elb.Tag{}
+	for key, value := range tags {
+		list = append(list, &elb.Tag{Key: aws.String(key), Value: aws.String(value)})
+	}
+	_, err := elbClient(resource.Region).AddTags(&elb.AddTagsInput{
+		LoadBalancerNames: []*string{&resource.Name},
+		Tags:              list,
+	})
+	return err
+}
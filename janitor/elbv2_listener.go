@@ -0,0 +1,59 @@
+package main
+
+import (
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/elbv2"
+)
+
+func init() {
+	registerResourceType("AWS::ElasticLoadBalancingV2::Listener", &resourceHandler{
+		CreatedBy:    []string{"CreateListener"},
+		ArnTypes:     []string{"elasticloadbalancing:listener"},
+		ByArn:        true,
+		TrailIdKeys:  []string{"listenerArn"},
+		Exists:       elasticLoadBalancingV2ListenerExists,
+		ExistsBatch:  elasticLoadBalancingV2ListenerExistsBatch,
+		BatchSize:    elbBatchSize,
+		Describe:     elbV2Describe,
+		Delete:       elasticLoadBalancingV2ListenerDelete,
+		DeleteScript: scriptCommand("elbv2 delete-listener --listener-arn"),
+		Tag:          elbV2Tag,
+	})
+}
+
+func elasticLoadBalancingV2ListenerExists(resource *Resource) (bool, error) {
+	found, errs := elasticLoadBalancingV2ListenerExistsBatch(resource.Region, []string{resource.Name})
+	return found[resource.Name], errs[resource.Name]
+}
+
+func elasticLoadBalancingV2ListenerExistsBatch(region string, ListenerIds []string) (map[string]bool, map[string]error) {
+	v("exists?", ListenerIds)
+
+	svc := elbV2Client(region)
+
+	// Only ARNs can be looked up
+	return existsSupported(ListenerIds, isArn, "not an ARN", func(arns []string) (map[string]bool, map[string]error) {
+		return existsBisect(arns, func(arns []string) (map[string]bool, error) {
+			result := map[string]bool{}
+			output, err := svc.DescribeListeners(&elbv2.DescribeListenersInput{
+				ListenerArns: aws.StringSlice(arns),
+			})
+			if err != nil {
+				return nil, err
+			}
+			for _, listener := range output.Listeners {
+				result[*listener.ListenerArn] = true
+			}
+			return result, nil
+		})
+	})
+}
+
+func elasticLoadBalancingV2ListenerDelete(resource *Resource) error {
+	svc := elbV2Client(resource.Region)
+
+	_, err := svc.DeleteListener(&elbv2.DeleteListenerInput{
+		ListenerArn: aws.String(resource.Name),
+	})
+	return err
+}
@@ -0,0 +1,86 @@
+package main
+
+import (
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/elbv2"
+)
+
+func init() {
+	registerResourceType("AWS::ElasticLoadBalancingV2::LoadBalancer", &resourceHandler{
+		CreatedBy:    []string{"CreateLoadBalancer"},
+		ArnTypes:     []string{"elasticloadbalancing:loadbalancer/app", "elasticloadbalancing:loadbalancer/net"},
+		ByArn:        true,
+		TrailIdKeys:  []string{"loadBalancerArn"},
+		NukeType:     "ELBv2",
+		Exists:       elasticLoadBalancingV2LoadBalancerExists,
+		ExistsBatch:  elasticLoadBalancingV2LoadBalancerExistsBatch,
+		BatchSize:    elbBatchSize,
+		Describe:     elasticLoadBalancingV2LoadBalancerDescribe,
+		Delete:       elasticLoadBalancingV2LoadBalancerDelete,
+		DeleteScript: scriptCommand("elbv2 delete-load-balancer --load-balancer-arn"),
+		Tag:          elbV2Tag,
+		DeleteAfter: []string{
+			"AWS::ElasticLoadBalancingV2::Listener",
+			"AWS::ElasticLoadBalancingV2::TargetGroup",
+		},
+	})
+}
+
+func elasticLoadBalancingV2LoadBalancerExists(resource *Resource) (bool, error) {
+	found, errs := elasticLoadBalancingV2LoadBalancerExistsBatch(resource.Region, []string{resource.Name})
+	return found[resource.Name], errs[resource.Name]
+}
+
+func elasticLoadBalancingV2LoadBalancerExistsBatch(region string, LoadBalancerIds []string) (map[string]bool, map[string]error) {
+	v("exists?", LoadBalancerIds)
+
+	svc := elbV2Client(region)
+
+	// Only ARNs can be looked up
+	return existsSupported(LoadBalancerIds, isArn, "not an ARN", func(arns []string) (map[string]bool, map[string]error) {
+		return existsBisect(arns, func(arns []string) (map[string]bool, error) {
+			result := map[string]bool{}
+			output, err := svc.DescribeLoadBalancers(&elbv2.DescribeLoadBalancersInput{
+				LoadBalancerArns: aws.StringSlice(arns),
+			})
+			if err != nil {
+				return nil, err
+			}
+			for _, loadBalancer := range output.LoadBalancers {
+				result[*loadBalancer.LoadBalancerArn] = true
+			}
+			return result, nil
+		})
+	})
+}
+
+func elasticLoadBalancingV2LoadBalancerDescribe(resource *Resource) (*ResourceDetails, error) {
+	svc := elbV2Client(resource.Region)
+
+	result, err := svc.DescribeLoadBalancers(&elbv2.DescribeLoadBalancersInput{
+		LoadBalancerArns: []*string{&resource.Name},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	for _, loadBalancer := range result.LoadBalancers {
+		details, err := elbV2Describe(resource)
+		if err != nil {
+			return nil, err
+		}
+		details.CreationTime = loadBalancer.CreatedTime
+		return details, nil
+	}
+
+	return nil, nil
+}
+
+func elasticLoadBalancingV2LoadBalancerDelete(resource *Resource) error {
+	svc := elbV2Client(resource.Region)
+
+	_, err := svc.DeleteLoadBalancer(&elbv2.DeleteLoadBalancerInput{
+		LoadBalancerArn: aws.String(resource.Name),
+	})
+	return err
+}
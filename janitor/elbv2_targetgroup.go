@@ -0,0 +1,63 @@
+package main
+
+import (
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/elbv2"
+)
+
+func init() {
+	registerResourceType("AWS::ElasticLoadBalancingV2::TargetGroup", &resourceHandler{
+		CreatedBy:    []string{"CreateTargetGroup"},
+		ArnTypes:     []string{"elasticloadbalancing:targetgroup"},
+		ByArn:        true,
+		TrailIdKeys:  []string{"targetGroupArn"},
+		NukeType:     "ELBv2TargetGroup",
+		Exists:       elasticLoadBalancingV2TargetGroupExists,
+		ExistsBatch:  elasticLoadBalancingV2TargetGroupExistsBatch,
+		BatchSize:    elbBatchSize,
+		Describe:     elbV2Describe,
+		Delete:       elasticLoadBalancingV2TargetGroupDelete,
+		DeleteScript: scriptCommand("elbv2 delete-target-group --target-group-arn"),
+		Tag:          elbV2Tag,
+		DeleteAfter: []string{
+			"AWS::ElasticLoadBalancingV2::Listener",
+		},
+	})
+}
+
+func elasticLoadBalancingV2TargetGroupExists(resource *Resource) (bool, error) {
+	found, errs := elasticLoadBalancingV2TargetGroupExistsBatch(resource.Region, []string{resource.Name})
+	return found[resource.Name], errs[resource.Name]
+}
+
+func elasticLoadBalancingV2TargetGroupExistsBatch(region string, TargetGroupIds []string) (map[string]bool, map[string]error) {
+	v("exists?", TargetGroupIds)
+
+	svc := elbV2Client(region)
+
+	// Only ARNs can be looked up
+	return existsSupported(TargetGroupIds, isArn, "not an ARN", func(arns []string) (map[string]bool, map[string]error) {
+		return existsBisect(arns, func(arns []string) (map[string]bool, error) {
+			result := map[string]bool{}
+			output, err := svc.DescribeTargetGroups(&elbv2.DescribeTargetGroupsInput{
+				TargetGroupArns: aws.StringSlice(arns),
+			})
+			if err != nil {
+				return nil, err
+			}
+			for _, targetGroup := range output.TargetGroups {
+				result[*targetGroup.TargetGroupArn] = true
+			}
+			return result, nil
+		})
+	})
+}
+
+func elasticLoadBalancingV2TargetGroupDelete(resource *Resource) error {
+	svc := elbV2Client(resource.Region)
+
+	_, err := svc.DeleteTargetGroup(&elbv2.DeleteTargetGroupInput{
+		TargetGroupArn: aws.String(resource.Name),
+	})
+	return err
+}
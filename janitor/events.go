@@ -0,0 +1,131 @@
+package main
+
+import (
+	"fmt"
+	"gopkg.in/yaml.v2"
+	"io/ioutil"
+	"regexp"
+	"strconv"
+	"sync"
+)
+
+// eventRule classifies CloudTrail events. Events excluded by a rule are not
+// used to find resources: read-only calls, deletions, ...
+// Every condition set must match. Event source and name are regular
+// expressions, unanchored.
+type eventRule struct {
+	Name        string `yaml:"name"`
+	Action      string `yaml:"action"`
+	ReadOnly    *bool  `yaml:"read_only"`
+	EventSource string `yaml:"event_source"`
+	EventName   string `yaml:"event_name"`
+
+	sourceRegexp *regexp.Regexp
+	nameRegexp   *regexp.Regexp
+}
+
+type eventRulesDocument struct {
+	Rules []*eventRule `yaml:"rules"`
+}
+
+var readOnlyTrue = true
+
+// defaultEventRules are evaluated after the rules of -event-rules.
+var defaultEventRules = []*eventRule{
+	{Name: "read-only", Action: "exclude", ReadOnly: &readOnlyTrue},
+	{Name: "read", Action: "exclude", EventName: "^(Describe|Get|List|Head|Lookup)"},
+	{Name: "delete", Action: "exclude", EventName: "^Delete"},
+	{Name: "release", Action: "exclude", EventName: "^(DeregisterTargets|TerminateInstances|RemoveRoleFromInstanceProfile)$"},
+}
+
+var eventRules []*eventRule
+
+// Number of events excluded by each rule
+var eventRuleCounts = map[string]int{}
+var eventRuleCountsMutex sync.Mutex
+
+// compileEventRules checks and compiles the rules of path, if not empty,
+// followed by the default rules.
+func compileEventRules(path string) ([]*eventRule, error) {
+	rules := []*eventRule{}
+	if path != "" {
+		content, err := ioutil.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+		file := &eventRulesDocument{}
+		if err := yaml.UnmarshalStrict(content, file); err != nil {
+			return nil, err
+		}
+		rules = append(rules, file.Rules...)
+	}
+	rules = append(rules, defaultEventRules...)
+
+	for i, rule := range rules {
+		if rule.Name == "" {
+			rule.Name = "rule " + strconv.Itoa(i+1)
+		}
+		if rule.Action != "include" && rule.Action != "exclude" {
+			return nil, fmt.Errorf("%s: action must be include or exclude", rule.Name)
+		}
+		var err error
+		if rule.sourceRegexp, err = regexp.Compile(rule.EventSource); err != nil {
+			return nil, fmt.Errorf("%s: event_source: %s", rule.Name, err)
+		}
+		if rule.nameRegexp, err = regexp.Compile(rule.EventName); err != nil {
+			return nil, fmt.Errorf("%s: event_name: %s", rule.Name, err)
+		}
+	}
+	return rules, nil
+}
+
+func (rule *eventRule) matches(eventSource string, eventName string, readOnly *bool) bool {
+	if rule.ReadOnly != nil && (readOnly == nil || *readOnly != *rule.ReadOnly) {
+		return false
+	}
+	return rule.sourceRegexp.MatchString(eventSource) &&
+		rule.nameRegexp.MatchString(eventName)
+}
+
+// IsInterestingEvent returns true if the resources of the event must be
+// searched. The first matching rule wins, events matching no rule are
+// interesting. Excluded events are counted per rule.
+func IsInterestingEvent(eventSource string, eventName string, readOnly *bool) bool {
+	for _, rule := range eventRules {
+		if !rule.matches(eventSource, eventName, readOnly) {
+			continue
+		}
+		if rule.Action == "exclude" {
+			eventRuleCountsMutex.Lock()
+			eventRuleCounts[rule.Name]++
+			eventRuleCountsMutex.Unlock()
+			return false
+		}
+		return true
+	}
+	return true
+}
+
+// resetEventRuleCounts returns the number of events excluded by each rule
+// since the last reset.
+func resetEventRuleCounts() map[string]int {
+	eventRuleCountsMutex.Lock()
+	defer eventRuleCountsMutex.Unlock()
+
+	counts := eventRuleCounts
+	eventRuleCounts = map[string]int{}
+	return counts
+}
+
+// parseReadOnly converts the readOnly attribute of LookupEvents, "true" or
+// "false".
+func parseReadOnly(readOnly *string) *bool {
+	if readOnly == nil {
+		return nil
+	}
+	value, err := strconv.ParseBool(*readOnly)
+	if err != nil {
+		return nil
+	}
+	return &value
+}
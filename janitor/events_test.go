@@ -0,0 +1,97 @@
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"reflect"
+	"testing"
+)
+
+func writeEventRules(t *testing.T, content string) string {
+	t.Helper()
+	file, err := ioutil.TempFile("", "event-rules")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+	if _, err := file.WriteString(content); err != nil {
+		t.Fatal(err)
+	}
+	return file.Name()
+}
+
+func TestEventRules(t *testing.T) {
+	path := writeEventRules(t, `rules:
+- name: keep-bucket-policies
+  action: include
+  event_source: ^s3\.
+  event_name: ^DeleteBucketPolicy$
+- action: exclude
+  event_name: ^CreateTags$
+`)
+	defer os.Remove(path)
+
+	defer func(rules []*eventRule) { eventRules = rules }(eventRules)
+	var err error
+	if eventRules, err = compileEventRules(path); err != nil {
+		t.Fatal(err)
+	}
+	resetEventRuleCounts()
+
+	readOnly, writes := true, false
+	tests := []struct {
+		source, name string
+		readOnly     *bool
+		interesting  bool
+	}{
+		{"ec2.amazonaws.com", "RunInstances", &writes, true},
+		{"ec2.amazonaws.com", "CreateTags", &writes, false},
+		{"ec2.amazonaws.com", "DescribeInstances", &readOnly, false},
+		// Old events have no readOnly attribute
+		{"ec2.amazonaws.com", "DescribeInstances", nil, false},
+		{"ec2.amazonaws.com", "DeleteVolume", &writes, false},
+		// The first matching rule wins over the defaults
+		{"s3.amazonaws.com", "DeleteBucketPolicy", &writes, true},
+		{"s3.amazonaws.com", "DeleteBucket", &writes, false},
+	}
+	for _, test := range tests {
+		if got := IsInterestingEvent(test.source, test.name, test.readOnly); got != test.interesting {
+			t.Errorf("%s %s: interesting %v, want %v", test.source, test.name, got, test.interesting)
+		}
+	}
+
+	want := map[string]int{"rule 2": 1, "read-only": 1, "read": 1, "delete": 2}
+	if counts := resetEventRuleCounts(); !reflect.DeepEqual(counts, want) {
+		t.Errorf("counts %v, want %v", counts, want)
+	}
+	if counts := resetEventRuleCounts(); len(counts) != 0 {
+		t.Errorf("counts %v after reset", counts)
+	}
+}
+
+func TestEventRulesErrors(t *testing.T) {
+	for _, content := range []string{
+		"rules:\n- action: drop\n",
+		"rules:\n- action: exclude\n  event_name: \"(\"\n",
+		"rules:\n- action: exclude\n  event: CreateTags\n",
+	} {
+		path := writeEventRules(t, content)
+		if _, err := compileEventRules(path); err == nil {
+			t.Errorf("%q compiled", content)
+		}
+		os.Remove(path)
+	}
+}
+
+func TestParseReadOnly(t *testing.T) {
+	value := func(s string) *bool { return parseReadOnly(&s) }
+	if got := value("true"); got == nil || !*got {
+		t.Error("true")
+	}
+	if got := value("false"); got == nil || *got {
+		t.Error("false")
+	}
+	if value("yes") != nil || parseReadOnly(nil) != nil {
+		t.Error("unknown values are not nil")
+	}
+}
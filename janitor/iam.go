@@ -1,79 +1,37 @@
 package main
 
 import (
-	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/aws"
 	"github.com/aws/aws-sdk-go/service/iam"
 	"strings"
 )
 
 var svcIam *iam.IAM
 
-func iamInstanceProfileExists(instanceprofileId string) bool {
-	v("exists?", instanceprofileId)
+// iamClient returns the IAM client. IAM is global, it uses the main session.
+func iamClient() *iam.IAM {
+	clientsMutex.Lock()
+	defer clientsMutex.Unlock()
 
-	// Skip full ids, test only InstanceProfile names
-	//if strings.Contains(instanceprofileId, "arn:aws:iam") {
-	//return false
-	//}
 	if svcIam == nil {
 		svcIam = iam.New(sess)
 	}
-
-	input := &iam.GetInstanceProfileInput{
-		InstanceProfileName: &instanceprofileId,
-	}
-	_, err := svcIam.GetInstanceProfile(input)
-	if err != nil {
-		if aerr, ok := err.(awserr.Error); ok {
-			switch aerr.Code() {
-			case "NoSuchEntity":
-				return false
-			case "ValidationError":
-				return false
-			default:
-				logErr.Println(aerr.Code())
-				logErr.Println(aerr.Error())
-			}
-		} else {
-			logErr.Println(err.Error())
-		}
-		return false
-	} else {
-		return true
-	}
+	return svcIam
 }
 
-func iamRoleExists(RoleId string) bool {
-	v("exists?", RoleId)
-
-	// Skip full ids, test only Role names
-	if strings.Contains(RoleId, "arn:aws:iam") {
-		return false
-	}
-	if svcIam == nil {
-		svcIam = iam.New(sess)
+func iamTags(tags map[string]string) []*iam.Tag {
+	list := []*iam.Tag{}
+	for key, value := range tags {
+		list = append(list, &iam.Tag{Key: aws.String(key), Value: aws.String(value)})
 	}
+	return list
+}
 
-	input := &iam.GetRoleInput{
-		RoleName: &RoleId,
-	}
-	_, err := svcIam.GetRole(input)
-	if err != nil {
-		if aerr, ok := err.(awserr.Error); ok {
-			switch aerr.Code() {
-			case "NoSuchEntity":
-				return false
-			case "ValidationError":
-				return false
-			default:
-				logErr.Println(aerr.Code())
-				logErr.Println(aerr.Error())
-			}
-		} else {
-			logErr.Println(err.Error())
-		}
-		return false
-	} else {
-		return true
+// iamArnName returns the name of an IAM ARN, ex: the name of
+// arn:aws:iam::123456789012:role/path/name, or name when it is not an ARN.
+func iamArnName(region string, name string) string {
+	if !strings.HasPrefix(name, "arn:aws:iam:") {
+		return name
 	}
+	return name[strings.LastIndex(name, "/")+1:]
 }
@@ -0,0 +1,111 @@
+package main
+
+import (
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/service/iam"
+)
+
+func init() {
+	registerResourceType("AWS::IAM::InstanceProfile", &resourceHandler{
+		CreatedBy:    []string{"CreateInstanceProfile"},
+		ArnTypes:     []string{"iam:instance-profile"},
+		TrailIdKeys:  []string{"instanceProfileName"},
+		NukeType:     "IAMInstanceProfile",
+		Canonical:    iamArnName,
+		Exists:       iamInstanceProfileExists,
+		Describe:     iamInstanceProfileDescribe,
+		Delete:       iamInstanceProfileDelete,
+		DeleteScript: iamInstanceProfileDeleteScript,
+		Tag:          iamInstanceProfileTag,
+		DeleteAfter: []string{
+			"AWS::EC2::Instance",
+		},
+	})
+}
+
+func iamInstanceProfileExists(resource *Resource) (bool, error) {
+	v("exists?", resource.Name)
+	svc := iamClient()
+
+	input := &iam.GetInstanceProfileInput{
+		InstanceProfileName: &resource.Name,
+	}
+	_, err := svc.GetInstanceProfile(input)
+	if err != nil {
+		if aerr, ok := err.(awserr.Error); ok {
+			switch aerr.Code() {
+			case "NoSuchEntity":
+				return false, nil
+			}
+		}
+		return false, err
+	}
+
+	return true, nil
+}
+
+func iamInstanceProfileDescribe(resource *Resource) (*ResourceDetails, error) {
+	svc := iamClient()
+
+	result, err := svc.GetInstanceProfile(&iam.GetInstanceProfileInput{
+		InstanceProfileName: &resource.Name,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	tags := map[string]string{}
+	for _, tag := range result.InstanceProfile.Tags {
+		tags[aws.StringValue(tag.Key)] = aws.StringValue(tag.Value)
+	}
+
+	return &ResourceDetails{
+		Tags:         tags,
+		CreationTime: result.InstanceProfile.CreateDate,
+	}, nil
+}
+
+func iamInstanceProfileDelete(resource *Resource) error {
+	svc := iamClient()
+
+	result, err := svc.GetInstanceProfile(&iam.GetInstanceProfileInput{
+		InstanceProfileName: &resource.Name,
+	})
+	if err != nil {
+		return err
+	}
+
+	for _, role := range result.InstanceProfile.Roles {
+		_, err = svc.RemoveRoleFromInstanceProfile(&iam.RemoveRoleFromInstanceProfileInput{
+			InstanceProfileName: &resource.Name,
+			RoleName:            role.RoleName,
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	_, err = svc.DeleteInstanceProfile(&iam.DeleteInstanceProfileInput{
+		InstanceProfileName: &resource.Name,
+	})
+	return err
+}
+
+func iamInstanceProfileDeleteScript(resource *Resource, aws string) []string {
+	name := shellQuote(resource.Name)
+	return []string{
+		"for role in $(" + aws + " iam get-instance-profile --instance-profile-name " + name + " --query 'InstanceProfile.Roles[].RoleName' --output text); do",
+		"  " + aws + " iam remove-role-from-instance-profile --instance-profile-name " + name + ` --role-name "$role"`,
+		"done",
+		aws + " iam delete-instance-profile --instance-profile-name " + name,
+	}
+}
+
+func iamInstanceProfileTag(resource *Resource, tags map[string]string) error {
+	_, err := iamClient().TagInstanceProfile(&iam.TagInstanceProfileInput{
+		InstanceProfileName: &resource.Name,
+		Tags:                iamTags(tags),
+	})
+	return err
+}
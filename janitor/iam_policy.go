@@ -0,0 +1,221 @@
+package main
+
+import (
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/service/iam"
+	"strings"
+	"sync"
+)
+
+func init() {
+	registerResourceType("AWS::IAM::Policy", &resourceHandler{
+		CreatedBy:    []string{"CreatePolicy"},
+		ArnTypes:     []string{"iam:policy"},
+		ByArn:        true,
+		TrailIdKeys:  []string{"policyArn"},
+		NukeType:     "IAMPolicy",
+		Exists:       iamPolicyExists,
+		Describe:     iamPolicyDescribe,
+		Delete:       iamPolicyDelete,
+		DeleteScript: iamPolicyDeleteScript,
+		Tag:          iamPolicyTag,
+		DeleteAfter: []string{
+			"AWS::IAM::Role",
+		},
+	})
+}
+
+var iamPolicyArns map[string]string
+var iamPolicyArnsMutex sync.Mutex
+
+// iamPolicyArn returns the ARN of a customer managed policy. CloudTrail
+// reports policies either by ARN or by name.
+func iamPolicyArn(policyId string) (string, error) {
+	if strings.HasPrefix(policyId, "arn:aws:iam") {
+		return policyId, nil
+	}
+
+	iamPolicyArnsMutex.Lock()
+	defer iamPolicyArnsMutex.Unlock()
+
+	if iamPolicyArns == nil {
+		arns := map[string]string{}
+		err := iamClient().ListPoliciesPages(
+			&iam.ListPoliciesInput{
+				Scope: aws.String(iam.PolicyScopeTypeLocal),
+			},
+			func(page *iam.ListPoliciesOutput, lastPage bool) bool {
+				for _, policy := range page.Policies {
+					arns[*policy.PolicyName] = *policy.Arn
+				}
+				return true
+			})
+		if err != nil {
+			return "", err
+		}
+		iamPolicyArns = arns
+	}
+
+	if arn, ok := iamPolicyArns[policyId]; ok {
+		return arn, nil
+	}
+	return "", awserr.New(iam.ErrCodeNoSuchEntityException, "policy "+policyId+" not found", nil)
+}
+
+func iamPolicyExists(resource *Resource) (bool, error) {
+	v("exists?", resource.Name)
+
+	// AWS managed policies are not ours
+	if strings.HasPrefix(resource.Name, "arn:aws:iam::aws:") {
+		return false, nil
+	}
+
+	arn, err := iamPolicyArn(resource.Name)
+	if err == nil {
+		_, err = iamClient().GetPolicy(&iam.GetPolicyInput{
+			PolicyArn: &arn,
+		})
+	}
+	if err != nil {
+		if aerr, ok := err.(awserr.Error); ok {
+			switch aerr.Code() {
+			case "NoSuchEntity":
+				return false, nil
+			}
+		}
+		return false, err
+	}
+
+	return true, nil
+}
+
+func iamPolicyDescribe(resource *Resource) (*ResourceDetails, error) {
+	arn, err := iamPolicyArn(resource.Name)
+	if err != nil {
+		return nil, err
+	}
+
+	result, err := iamClient().GetPolicy(&iam.GetPolicyInput{
+		PolicyArn: &arn,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	tags := map[string]string{}
+	for _, tag := range result.Policy.Tags {
+		tags[aws.StringValue(tag.Key)] = aws.StringValue(tag.Value)
+	}
+
+	return &ResourceDetails{
+		Tags:         tags,
+		CreationTime: result.Policy.CreateDate,
+	}, nil
+}
+
+func iamPolicyDelete(resource *Resource) error {
+	svc := iamClient()
+
+	arn, err := iamPolicyArn(resource.Name)
+	if err != nil {
+		return err
+	}
+
+	// A policy cannot be deleted while it is attached or has other versions
+	entities, err := svc.ListEntitiesForPolicy(&iam.ListEntitiesForPolicyInput{
+		PolicyArn: &arn,
+	})
+	if err != nil {
+		return err
+	}
+	for _, role := range entities.PolicyRoles {
+		_, err = svc.DetachRolePolicy(&iam.DetachRolePolicyInput{
+			PolicyArn: &arn,
+			RoleName:  role.RoleName,
+		})
+		if err != nil {
+			return err
+		}
+	}
+	for _, user := range entities.PolicyUsers {
+		_, err = svc.DetachUserPolicy(&iam.DetachUserPolicyInput{
+			PolicyArn: &arn,
+			UserName:  user.UserName,
+		})
+		if err != nil {
+			return err
+		}
+	}
+	for _, group := range entities.PolicyGroups {
+		_, err = svc.DetachGroupPolicy(&iam.DetachGroupPolicyInput{
+			PolicyArn: &arn,
+			GroupName: group.GroupName,
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	versions, err := svc.ListPolicyVersions(&iam.ListPolicyVersionsInput{
+		PolicyArn: &arn,
+	})
+	if err != nil {
+		return err
+	}
+	for _, version := range versions.Versions {
+		if *version.IsDefaultVersion {
+			continue
+		}
+		_, err = svc.DeletePolicyVersion(&iam.DeletePolicyVersionInput{
+			PolicyArn: &arn,
+			VersionId: version.VersionId,
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	_, err = svc.DeletePolicy(&iam.DeletePolicyInput{
+		PolicyArn: &arn,
+	})
+	return err
+}
+
+func iamPolicyDeleteScript(resource *Resource, aws string) []string {
+	lines := []string{}
+	if strings.HasPrefix(resource.Name, "arn:") {
+		lines = append(lines, "arn="+shellQuote(resource.Name))
+	} else {
+		lines = append(lines, "arn=$("+aws+` iam list-policies --scope Local --query "Policies[?PolicyName=='`+resource.Name+`'].Arn | [0]" --output text)`)
+	}
+	entities := aws + ` iam list-entities-for-policy --policy-arn "$arn" --output text --query `
+	return append(lines,
+		"for role in $("+entities+"'PolicyRoles[].RoleName'); do",
+		"  "+aws+` iam detach-role-policy --policy-arn "$arn" --role-name "$role"`,
+		"done",
+		"for user in $("+entities+"'PolicyUsers[].UserName'); do",
+		"  "+aws+` iam detach-user-policy --policy-arn "$arn" --user-name "$user"`,
+		"done",
+		"for group in $("+entities+"'PolicyGroups[].GroupName'); do",
+		"  "+aws+` iam detach-group-policy --policy-arn "$arn" --group-name "$group"`,
+		"done",
+		"for version in $("+aws+` iam list-policy-versions --policy-arn "$arn" --query 'Versions[?!IsDefaultVersion].VersionId' --output text); do`,
+		"  "+aws+` iam delete-policy-version --policy-arn "$arn" --version-id "$version"`,
+		"done",
+		aws+` iam delete-policy --policy-arn "$arn"`,
+	)
+}
+
+func iamPolicyTag(resource *Resource, tags map[string]string) error {
+	arn, err := iamPolicyArn(resource.Name)
+	if err != nil {
+		return err
+	}
+
+	_, err = iamClient().TagPolicy(&iam.TagPolicyInput{
+		PolicyArn: &arn,
+		Tags:      iamTags(tags),
+	})
+	return err
+}
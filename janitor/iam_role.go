@@ -0,0 +1,150 @@
+package main
+
+import (
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/service/iam"
+)
+
+func init() {
+	registerResourceType("AWS::IAM::Role", &resourceHandler{
+		CreatedBy:    []string{"CreateRole"},
+		ArnTypes:     []string{"iam:role"},
+		TrailIdKeys:  []string{"roleName"},
+		NukeType:     "IAMRole",
+		Canonical:    iamArnName,
+		Exists:       iamRoleExists,
+		Describe:     iamRoleDescribe,
+		Delete:       iamRoleDelete,
+		DeleteScript: iamRoleDeleteScript,
+		Tag:          iamRoleTag,
+		DeleteAfter: []string{
+			"AWS::IAM::InstanceProfile",
+		},
+	})
+}
+
+func iamRoleExists(resource *Resource) (bool, error) {
+	v("exists?", resource.Name)
+	svc := iamClient()
+
+	input := &iam.GetRoleInput{
+		RoleName: &resource.Name,
+	}
+	_, err := svc.GetRole(input)
+	if err != nil {
+		if aerr, ok := err.(awserr.Error); ok {
+			switch aerr.Code() {
+			case "NoSuchEntity":
+				return false, nil
+			}
+		}
+		return false, err
+	}
+
+	return true, nil
+}
+
+func iamRoleDescribe(resource *Resource) (*ResourceDetails, error) {
+	svc := iamClient()
+
+	result, err := svc.GetRole(&iam.GetRoleInput{
+		RoleName: &resource.Name,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	tags := map[string]string{}
+	for _, tag := range result.Role.Tags {
+		tags[aws.StringValue(tag.Key)] = aws.StringValue(tag.Value)
+	}
+
+	return &ResourceDetails{
+		Tags:         tags,
+		CreationTime: result.Role.CreateDate,
+	}, nil
+}
+
+func iamRoleDelete(resource *Resource) error {
+	svc := iamClient()
+
+	// A role cannot be deleted while it is still part of an instance profile
+	// or has policies attached.
+	profiles, err := svc.ListInstanceProfilesForRole(&iam.ListInstanceProfilesForRoleInput{
+		RoleName: &resource.Name,
+	})
+	if err != nil {
+		return err
+	}
+	for _, profile := range profiles.InstanceProfiles {
+		_, err = svc.RemoveRoleFromInstanceProfile(&iam.RemoveRoleFromInstanceProfileInput{
+			InstanceProfileName: profile.InstanceProfileName,
+			RoleName:            &resource.Name,
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	attached, err := svc.ListAttachedRolePolicies(&iam.ListAttachedRolePoliciesInput{
+		RoleName: &resource.Name,
+	})
+	if err != nil {
+		return err
+	}
+	for _, policy := range attached.AttachedPolicies {
+		_, err = svc.DetachRolePolicy(&iam.DetachRolePolicyInput{
+			PolicyArn: policy.PolicyArn,
+			RoleName:  &resource.Name,
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	inline, err := svc.ListRolePolicies(&iam.ListRolePoliciesInput{
+		RoleName: &resource.Name,
+	})
+	if err != nil {
+		return err
+	}
+	for _, policyName := range inline.PolicyNames {
+		_, err = svc.DeleteRolePolicy(&iam.DeleteRolePolicyInput{
+			PolicyName: policyName,
+			RoleName:   &resource.Name,
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	_, err = svc.DeleteRole(&iam.DeleteRoleInput{
+		RoleName: &resource.Name,
+	})
+	return err
+}
+
+func iamRoleDeleteScript(resource *Resource, aws string) []string {
+	name := shellQuote(resource.Name)
+	return []string{
+		"for profile in $(" + aws + " iam list-instance-profiles-for-role --role-name " + name + " --query 'InstanceProfiles[].InstanceProfileName' --output text); do",
+		"  " + aws + ` iam remove-role-from-instance-profile --instance-profile-name "$profile" --role-name ` + name,
+		"done",
+		"for policy in $(" + aws + " iam list-attached-role-policies --role-name " + name + " --query 'AttachedPolicies[].PolicyArn' --output text); do",
+		"  " + aws + " iam detach-role-policy --role-name " + name + ` --policy-arn "$policy"`,
+		"done",
+		"for policy in $(" + aws + " iam list-role-policies --role-name " + name + " --query 'PolicyNames[]' --output text); do",
+		"  " + aws + " iam delete-role-policy --role-name " + name + ` --policy-name "$policy"`,
+		"done",
+		aws + " iam delete-role --role-name " + name,
+	}
+}
+
+func iamRoleTag(resource *Resource, tags map[string]string) error {
+	_, err := iamClient().TagRole(&iam.TagRoleInput{
+		RoleName: &resource.Name,
+		Tags:     iamTags(tags),
+	})
+	return err
+}
@@ -0,0 +1,25 @@
+package main
+
+import (
+	"strings"
+)
+
+// CloudTrail lookup attributes accepted by -lookup, besides Username
+var lookupAttributes = []string{
+	"AccessKeyId",
+	"EventName",
+	"EventSource",
+	"ResourceName",
+	"ResourceType",
+	"Username",
+}
+
+// lookupAttribute returns the CloudTrail lookup attribute of a principal:
+// principals of -lookup are Attribute=value, the others are user names, or
+// the session names of assumed roles.
+func lookupAttribute(principal string) (string, string) {
+	if i := strings.Index(principal, "="); i >= 0 && contains(lookupAttributes, principal[:i]) {
+		return principal[:i], principal[i+1:]
+	}
+	return "Username", principal
+}
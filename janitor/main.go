@@ -8,40 +8,108 @@ See doc at https://docs.aws.amazon.com/sdk-for-go/api/
 
 DONE: list all events done by user and his instances (master0 usually)
 DONE: add a recursive option to include all resources created by instances
+DONE: recursion over instances, IAM users and role sessions, to -depth levels, each principal searched once
+DONE: provenance tree: event and chain of principals that introduced each resource
+DONE: resumable CloudTrail scans (-checkpoint, -resume)
+DONE: search by any CloudTrail lookup attribute (-lookup AccessKeyId=..., ResourceName=...)
+DONE: many users in one run (-users file of user,start-time)
+DONE: cross-account audits through assumed roles (-role-arn, -accounts, -all-accounts)
+DONE: configurable event classification rules (-event-rules), with read-only flag, event source and name
 DONE: make concurrency work (throttling), catch exceptions and retry using (exponentially) delayed retries
 DONE: Split into several files for readability/maintenance
 DONE: dry-mode: print resources still existing => first step: this will be emailed to us after deletion
 DONE: filter out possible false-positive, stupid ex: a user describe our top root route53 domain, we don't want to delete the domain! For now exclude *Describe* actions. Need to comeup with a whitelist of actions.
 DONE: make sure concurrency work again with all the *Exists() functions that use different API (ec2, iam, ...)
-TODO: all a all-region option to control all possible AWS regions
-TODO: delete all resources, including dynamic resources (gp2 storage class, elb...)
-TODO: filter out resources if creation time is before time passed as argument
+DONE: check existence with a pool of workers (-concurrency), rate-limited per service with a shared backoff
+DONE: all-region option to control all possible AWS regions (-all-regions, -regions)
+DONE: delete mode: delete resources still existing, in dependency order, with retries
+DONE: report resources whose existence could not be verified instead of treating errors as gone
+DONE: protection policy (-policy): allow/deny rules by type, id, tags, account and region
+DONE: tags discovery backend (-backend=tags|all -tag key=value), with the Resource Groups Tagging API
+DONE: read archived CloudTrail log files from a directory or S3 (-trail) instead of LookupEvents
+DONE: include dynamic resources (gp2 storage class, elb...) of the clusters of the user, by their kubernetes.io/cluster/<infra id> tag (-clusters)
+DONE: filter out resources if creation time is before time passed as argument
+DONE: quarantine (-quarantine): tag, stop instances, revoke security group ingress; reap after a grace period (-reap, -grace)
+DONE: reviewable shell script of aws CLI delete commands, in dependency order (-emit=script)
+DONE: aws-nuke configuration deleting exactly the resources found (-emit=aws-nuke)
+DONE: estimated hourly/monthly cost of the resources from a price table (-prices), -min-cost to collapse cheap ones
+DONE: snapshots backing the AMIs, reported under their AMI and deleted after it
 */
 
 package main
 
 import (
 	"flag"
+	"fmt"
 	"github.com/aws/aws-sdk-go/aws"
 	"github.com/aws/aws-sdk-go/aws/awserr"
-	"github.com/aws/aws-sdk-go/aws/client"
 	"github.com/aws/aws-sdk-go/aws/session"
 	"github.com/aws/aws-sdk-go/service/cloudtrail"
 	"github.com/aws/aws-sdk-go/service/connect"
+	"github.com/aws/aws-sdk-go/service/ec2"
+	"io"
 	"io/ioutil"
 	"log"
 	"math/rand"
 	"os"
 	"strings"
+	"sync"
 	"time"
 )
 
 var userName string
+var usersFile string
+var roleArn string
+var roleName string
+var accountsString string
+var allAccounts bool
 var startTime time.Time
+
+// endTime is the end of the events searched, zero for now
+var endTime time.Time
 var debug bool
 var recursive bool
+var depth int
 var showevents bool
 var quietmode bool
+var deleteMode bool
+var quarantineMode bool
+var reapMode bool
+var clusters bool
+var gracePeriod time.Duration
+
+// reportID is the id of the report of the run, quarantined resources are
+// tagged with it.
+var reportID string
+var showDetails bool
+var concurrency int
+var outputFormat string
+var allRegions bool
+var regionsString string
+var policyFile string
+var eventRulesFile string
+var backend string
+var trailLocation string
+var checkpointFile string
+var resume bool
+var pricesFile string
+var minCost float64
+var emitFormat string
+var nukeBlocklist string
+var tags stringsFlag
+var lookups stringsFlag
+
+// stringsFlag collects the values of a flag given several times, ex: -tag.
+type stringsFlag []string
+
+func (t *stringsFlag) String() string {
+	return strings.Join(*t, ",")
+}
+
+func (t *stringsFlag) Set(value string) error {
+	*t = append(*t, value)
+	return nil
+}
 
 // Logs
 var logErr *log.Logger
@@ -51,32 +119,171 @@ var logReport *log.Logger
 
 // clients
 
-var sess client.ConfigProvider
-var svcCloudtrail *cloudtrail.CloudTrail
+var sess *session.Session
+var sessions = map[string]*session.Session{}
+var sessionsMutex sync.Mutex
+var svcCloudtrail = map[string]*cloudtrail.CloudTrail{}
+
+// clientsMutex protects the client caches of all services, they are used by
+// concurrent goroutines.
+var clientsMutex sync.Mutex
+
+// defaultRegion is the region of the main session, used for global services.
+var defaultRegion string
+
+// globalRegion is the region reported for IAM, S3 and Route53 resources.
+const globalRegion = "global"
+
+// globalEventsRegion is the region CloudTrail logs the events of the global
+// services in, ex: IAM, Route53.
+const globalEventsRegion = "us-east-1"
+
+// Resource is a resource found in CloudTrail, along with the region it lives in
+// and the event that introduced it.
+type Resource struct {
+	Type      string    `json:"type" yaml:"type"`
+	Name      string    `json:"name" yaml:"name"`
+	Region    string    `json:"region" yaml:"region"`
+	EventName string    `json:"event_name" yaml:"event_name"`
+	EventTime time.Time `json:"event_time" yaml:"event_time"`
+	EventID   string    `json:"event_id,omitempty" yaml:"event_id,omitempty"`
+	// Principal is the user or the instance the resource is attributed to
+	Principal string `json:"attributed_to" yaml:"attributed_to"`
+	// Chain is the chain of principals from the user to Principal, ex:
+	// user, instance id, with -r
+	Chain   []string         `json:"chain" yaml:"chain"`
+	Details *ResourceDetails `json:"details,omitempty" yaml:"details,omitempty"`
+	// Error is the error code when the existence could not be verified
+	Error string `json:"error,omitempty" yaml:"error,omitempty"`
+	// Users lists the users the resource was found for, with -users
+	Users []string `json:"users,omitempty" yaml:"users,omitempty"`
+	// startTime is the start time of the search that found the resource
+	startTime time.Time
+	// FoundBy lists the discovery backends that found the resource
+	FoundBy []string `json:"found_by" yaml:"found_by"`
+	// ProtectedBy is the name of the policy rule protecting the resource,
+	// the reason its handler keeps it, or its parent when the parent is
+	// not deleted
+	ProtectedBy string `json:"protected_by,omitempty" yaml:"protected_by,omitempty"`
+	// Cost is the estimated cost of the resource, nil if free or unknown
+	Cost *Cost `json:"cost,omitempty" yaml:"cost,omitempty"`
+	// BelowMinCost is true when the resource is cheaper than -min-cost, it
+	// is listed without details in the text report and still deleted
+	BelowMinCost bool `json:"below_min_cost,omitempty" yaml:"below_min_cost,omitempty"`
+	// Parent is the name of the resource in the same region this one
+	// belongs to, ex: the AMI of a snapshot
+	Parent string `json:"parent,omitempty" yaml:"parent,omitempty"`
+	// ParentType is the type of Parent
+	ParentType string `json:"parent_type,omitempty" yaml:"parent_type,omitempty"`
+}
 
 var maxRetries int = 100
 
 func parseFlags() {
 	var startTimeString string
+	var endTimeString string
 	// Option to show event
 	flag.BoolVar(&debug, "v", false, "Whether to show DEBUG info")
 	flag.BoolVar(&showevents, "showevents", false, "Whether to show Events info")
 	flag.BoolVar(&quietmode, "quiet", false, "Show only report")
-	flag.BoolVar(&recursive, "r", false, "Perform action recursively, search for resources touched or created by instances which themselves were created by the user")
+	flag.BoolVar(&showDetails, "details", false, "Show owner, creation time and tags of the resources in the report")
+	flag.BoolVar(&deleteMode, "delete", false, "Delete the resources still existing, in dependency order. Default is dry-run: only print them")
+	flag.BoolVar(&quarantineMode, "quarantine", false, "Quarantine the resources still existing instead of deleting them: tag them with janitor:quarantined-at and janitor:report-id, stop instances, revoke the ingress rules of security groups")
+	flag.BoolVar(&reapMode, "reap", false, "Delete the resources quarantined more than -grace ago, leave the others alone")
+	flag.DurationVar(&gracePeriod, "grace", 7*24*time.Hour, "With -reap, time the resources stay quarantined before they are deleted")
+	flag.IntVar(&concurrency, "concurrency", 10, "Number of resources checked for existence concurrently")
+	flag.BoolVar(&recursive, "r", false, "Perform action recursively, search for resources touched or created by instances, IAM users and role sessions which themselves were created by the user")
+	flag.BoolVar(&clusters, "clusters", false, "Also search the resources created by the Kubernetes/OpenShift clusters of the resources found, tagged kubernetes.io/cluster/<infra id>=owned, and the records of their domain")
+	flag.IntVar(&depth, "depth", 1, "With -r, number of levels of principals to follow, 0 for no limit")
+	flag.BoolVar(&allRegions, "all-regions", false, "Search all the regions enabled in the account")
+	flag.StringVar(&regionsString, "regions", "", "Comma-separated list of regions to search, ex: us-east-1,eu-west-1. Default is AWS_REGION")
+	flag.StringVar(&eventRulesFile, "event-rules", "", "YAML file of include/exclude rules of CloudTrail events, evaluated before the default rules")
+	flag.StringVar(&policyFile, "policy", "", "YAML file of allow/deny rules, resources matching a deny rule are never reported nor deleted")
+	flag.StringVar(&pricesFile, "prices", "", "YAML file of prices overriding the bundled us-east-1 price table used to estimate costs")
+	flag.Float64Var(&minCost, "min-cost", 0, "Collapse the resources whose estimated cost is less than this many USD a month into a short list at the end of the text report, they are still deleted")
+	flag.StringVar(&outputFormat, "output", "text", "Format of the report: text, json, yaml or csv")
+	flag.StringVar(&emitFormat, "emit", "", "Write a document to stdout instead of the report, which goes to stderr: aws-nuke for an aws-nuke configuration deleting the resources found, script for a shell script of aws CLI commands deleting them")
+	flag.StringVar(&nukeBlocklist, "nuke-blocklist", "", "Comma-separated list of accounts aws-nuke must never touch, required by aws-nuke and -emit=aws-nuke")
+	flag.StringVar(&backend, "backend", backendCloudtrail, "Discovery backend: cloudtrail, tags (needs -tag), or all to merge both")
+	flag.StringVar(&trailLocation, "trail", "", "Read the CloudTrail log files archived in a directory or in s3://bucket/prefix instead of calling LookupEvents")
+	flag.StringVar(&checkpointFile, "checkpoint", "", "Save the progress of the CloudTrail scans to this file")
+	flag.BoolVar(&resume, "resume", false, "Continue the CloudTrail scans from the -checkpoint file of an interrupted run")
+	flag.Var(&tags, "tag", "Tag key=value of the resources to find with the tags backend, ex: guid=abc123. Repeat to require several tags")
 	flag.StringVar(&userName, "u", "", "The username that created the resources")
+	flag.StringVar(&roleArn, "role-arn", "", "Audit the account of this role, assumed with the credentials of the environment")
+	flag.StringVar(&accountsString, "accounts", "", "Comma-separated list of accounts to audit, through the -role-name role of each")
+	flag.BoolVar(&allAccounts, "all-accounts", false, "Audit all the active accounts of the organization, through the -role-name role of each")
+	flag.StringVar(&roleName, "role-name", "OrganizationAccountAccessRole", "Role assumed in the accounts of -accounts and -all-accounts")
+	flag.StringVar(&usersFile, "users", "", "File of user,start-time lines to search in one run, - for stdin")
+	flag.Var(&lookups, "lookup", "Search the events of a CloudTrail lookup attribute instead of, or along with, -u, ex: AccessKeyId=AKIA..., ResourceName=my-bucket. Attributes: "+strings.Join(lookupAttributes, ", ")+". Repeat for several lookups")
 	flag.StringVar(&startTimeString, "t", "", "Filter event starting at that time. It's RFC3339 or ISO8601 time, ex: 2019-01-14T09:04:25.392000+00:00")
+	flag.StringVar(&endTimeString, "end", "", "Filter event ending at that time, same format as -t. Default is now")
 
 	flag.Parse()
 
-	if userName == "" || startTimeString == "" {
+	switch outputFormat {
+	case "text", "json", "yaml", "csv":
+	default:
+		flag.PrintDefaults()
+		os.Exit(2)
+	}
+
+	switch backend {
+	case backendCloudtrail, backendTags, "all":
+	default:
+		flag.PrintDefaults()
+		os.Exit(2)
+	}
+
+	switch emitFormat {
+	case "":
+	case "script":
+		if outputFormat != "text" {
+			flag.PrintDefaults()
+			os.Exit(2)
+		}
+	case "aws-nuke":
+		if nukeBlocklist == "" || outputFormat != "text" {
+			flag.PrintDefaults()
+			os.Exit(2)
+		}
+	default:
+		flag.PrintDefaults()
+		os.Exit(2)
+	}
+
+	for _, lookup := range lookups {
+		if key, _ := lookupAttribute(lookup); key == "Username" && !strings.HasPrefix(lookup, "Username=") {
+			flag.PrintDefaults()
+			os.Exit(2)
+		}
+	}
+
+	// -t is the start time of -u, -lookup and -tag, -users have their own
+	needsStartTime := userName != "" || len(lookups) > 0 || backend != backendCloudtrail
+	if (userName == "" && len(lookups) == 0 && usersFile == "" && backend != backendTags) ||
+		(len(tags) == 0 && backend != backendCloudtrail) ||
+		(startTimeString == "" && needsStartTime) ||
+		concurrency < 1 || depth < 0 || minCost < 0 ||
+		(deleteMode && quarantineMode) || (deleteMode && reapMode) || (quarantineMode && reapMode) ||
+		(resume && checkpointFile == "") {
 		flag.PrintDefaults()
 		os.Exit(2)
 	}
 	var err error
-	startTime, err = time.Parse(time.RFC3339, startTimeString)
-	if err != nil {
-		logErr.Println("Error parsing start time")
-		os.Exit(1)
+	if startTimeString != "" {
+		startTime, err = time.Parse(time.RFC3339, startTimeString)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "Error parsing start time")
+			os.Exit(1)
+		}
+	}
+	if endTimeString != "" {
+		endTime, err = time.Parse(time.RFC3339, endTimeString)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "Error parsing end time")
+			os.Exit(1)
+		}
 	}
 }
 
@@ -86,100 +293,312 @@ func v(line ...interface{}) {
 	}
 }
 
-func IsInterestingEvent(eventName string) bool {
-	switch eventName {
-	case
-		"DeregisterTargets",
-		"TerminateInstances",
-		"RemoveRoleFromInstanceProfile":
-		return false
+// filterExisting returns the resources that still exist, and the resources
+// whose existence could not be verified, with their Error set.
+// Resources of a type without handler are not checked, they are counted per
+// type in unsupported.
+// Resources of the same type and region are checked in batches when the
+// handler supports it.
+// Existence is checked by a pool of concurrency workers, the rate of API
+// calls is limited per service by the rate limiter of the session.
+func filterExisting(resources []*Resource) (result []*Resource, unverified []*Resource, unsupported map[string]int) {
+	result = []*Resource{}
+	unverified = []*Resource{}
+	unsupported = map[string]int{}
+	exists := map[*Resource]bool{}
+	errs := map[*Resource]error{}
+	var existsMutex sync.Mutex
+
+	jobs := make(chan func())
+	var wg sync.WaitGroup
+	for w := 0; w < concurrency; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for job := range jobs {
+				job()
+			}
+		}()
 	}
 
-	if strings.Contains(eventName, "Describe") {
-		return false
+	// Group the resources to check in batches by type and region
+	groupKeys := []string{}
+	groups := map[string][]*Resource{}
+
+	for _, resource := range resources {
+		handler, ok := resourceHandlers[resource.Type]
+		if !ok {
+			unsupported[resource.Type]++
+			continue
+		}
+
+		if handler.ExistsBatch != nil {
+			key := resource.Type + " " + resource.Region
+			if _, ok := groups[key]; !ok {
+				groupKeys = append(groupKeys, key)
+			}
+			groups[key] = append(groups[key], resource)
+			continue
+		}
+
+		resource := resource
+		jobs <- func() {
+			found, err := handler.Exists(resource)
+			existsMutex.Lock()
+			exists[resource] = found
+			errs[resource] = err
+			existsMutex.Unlock()
+		}
 	}
-	if strings.Contains(eventName, "Delete") {
-		return false
+
+	for _, key := range groupKeys {
+		group := groups[key]
+		handler := resourceHandlers[group[0].Type]
+		region := group[0].Region
+		byName := map[string]*Resource{}
+		names := []string{}
+		for _, resource := range group {
+			byName[resource.Name] = resource
+			names = append(names, resource.Name)
+		}
+
+		for _, batch := range chunks(names, handler.BatchSize) {
+			batch := batch
+			jobs <- func() {
+				found, batchErrs := handler.ExistsBatch(region, batch)
+				existsMutex.Lock()
+				for _, name := range batch {
+					exists[byName[name]] = found[name]
+					errs[byName[name]] = batchErrs[name]
+				}
+				existsMutex.Unlock()
+			}
+		}
 	}
-	return true
+
+	close(jobs)
+	wg.Wait()
+
+	for _, resource := range resources {
+		if err := errs[resource]; err != nil {
+			v("could not verify", resource.Type, resource.Name, err.Error())
+			resource.Error = errorCode(err)
+			unverified = append(unverified, resource)
+		} else if exists[resource] {
+			result = append(result, resource)
+		}
+	}
+
+	return result, unverified, unsupported
 }
 
-func resourceExists(resource *cloudtrail.Resource) bool {
-	switch *resource.ResourceType {
-	case "AWS::EC2::Instance":
-		return ec2InstanceExists(*resource.ResourceName)
-	case "AWS::EC2::Volume":
-		return ec2VolumeExists(*resource.ResourceName)
-	case "AWS::EC2::NatGateway":
-		return ec2NatGatewayExists(*resource.ResourceName)
-	case "AWS::EC2::Subnet":
-		return ec2SubnetExists(*resource.ResourceName)
-	case "AWS::EC2::EIP":
-		return ec2EIPExists(*resource.ResourceName)
-	case "AWS::EC2::RouteTable":
-		return ec2RouteTableExists(*resource.ResourceName)
-	case "AWS::EC2::SecurityGroup":
-		return ec2SecurityGroupExists(*resource.ResourceName)
-	case "AWS::EC2::NetworkInterface":
-		return ec2NetworkInterfaceExists(*resource.ResourceName)
-	case "AWS::EC2::VPC":
-		return ec2VpcExists(*resource.ResourceName)
-	case "AWS::EC2::InternetGateway":
-		return ec2InternetGatewayExists(*resource.ResourceName)
-	case "AWS::EC2::Ami":
-		return ec2ImageExists(*resource.ResourceName)
-	case "AWS::IAM::InstanceProfile":
-		return iamInstanceProfileExists(*resource.ResourceName)
-	case "AWS::IAM::Role":
-		return iamRoleExists(*resource.ResourceName)
-	case "AWS::ElasticLoadBalancing::LoadBalancer":
-		return elasticLoadBalancingLoadBalancerExists(*resource.ResourceName)
-	case "AWS::ElasticLoadBalancingV2::LoadBalancer":
-		return elasticLoadBalancingV2LoadBalancerExists(*resource.ResourceName)
-	case "AWS::ElasticLoadBalancingV2::Listener":
-		return elasticLoadBalancingV2ListenerExists(*resource.ResourceName)
-	case "AWS::ElasticLoadBalancingV2::TargetGroup":
-		return elasticLoadBalancingV2TargetGroupExists(*resource.ResourceName)
-	case "AWS::S3::Bucket":
-		return s3BucketExists(*resource.ResourceName)
-
-		/* TODO:
-		   23 AWS::EC2::SubnetRouteTableAssociation
-		    3 AWS::IAM::Policy
-		*/
-	default:
-		logErr.Println("Type", *resource.ResourceType, "not supported")
+// errorCode returns the AWS error code of err, or its message for other
+// errors, ex: network errors.
+func errorCode(err error) string {
+	if aerr, ok := err.(awserr.Error); ok {
+		return aerr.Code()
 	}
+	return err.Error()
+}
 
-	return false
+// isGlobalType returns true for resource types that do not live in a region.
+// CloudTrail may report them from several regions, they must be checked only once.
+func isGlobalType(resourceType string) bool {
+	return strings.HasPrefix(resourceType, "AWS::IAM::") ||
+		strings.HasPrefix(resourceType, "AWS::S3::") ||
+		strings.HasPrefix(resourceType, "AWS::Route53::")
+}
+
+func cloudtrailClient(region string) *cloudtrail.CloudTrail {
+	clientsMutex.Lock()
+	defer clientsMutex.Unlock()
+
+	if svcCloudtrail[region] == nil {
+		svcCloudtrail[region] = cloudtrail.New(regionSession(region))
+	}
+	return svcCloudtrail[region]
 }
 
-func filterExisting(resources []*cloudtrail.Resource) (result []*cloudtrail.Resource) {
-	result = []*cloudtrail.Resource{}
+func regionSession(region string) *session.Session {
+	if region == globalRegion || region == defaultRegion {
+		return sess
+	}
+
+	sessionsMutex.Lock()
+	defer sessionsMutex.Unlock()
+
+	if sessions[region] == nil {
+		sessions[region] = sess.Copy(&aws.Config{Region: aws.String(region)})
+	}
+	return sessions[region]
+}
 
+// searchRegions returns the regions to search, from the -regions and
+// -all-regions flags. Default is the region of the session.
+func searchRegions() ([]string, error) {
+	if allRegions {
+		svcGlob := ec2.New(sess)
+		result, err := svcGlob.DescribeRegions(&ec2.DescribeRegionsInput{})
+		if err != nil {
+			return nil, err
+		}
+		regions := []string{}
+		for _, region := range result.Regions {
+			regions = append(regions, *region.RegionName)
+		}
+		return regions, nil
+	}
+
+	if regionsString != "" {
+		regions := []string{}
+		for _, region := range strings.Split(regionsString, ",") {
+			if region = strings.TrimSpace(region); region != "" {
+				regions = append(regions, region)
+			}
+		}
+		return regions, nil
+	}
+
+	return []string{defaultRegion}, nil
+}
+
+// resourceKey returns the key of a resource in a run: resources of different
+// types may have the same name in a region, ex: a role and its instance
+// profile.
+func resourceKey(resourceType string, region string, name string) string {
+	return resourceType + " " + region + " " + name
+}
+
+// canonicalName returns the name the handler of the type knows a resource
+// by, ex: the name of a role ARN.
+func canonicalName(resourceType string, region string, name string) string {
+	if handler, ok := resourceHandlers[resourceType]; ok && handler.Canonical != nil {
+		return handler.Canonical(region, name)
+	}
+	return name
+}
+
+// mergeResources appends resources to result, skipping those already in it,
+// by their type, region and canonical name.
+// The backends and users that found a skipped resource are added to the one
+// in result.
+func mergeResources(result []*Resource, resources []*Resource) []*Resource {
+	seen := map[string]*Resource{}
+	for _, resource := range result {
+		resource.Name = canonicalName(resource.Type, resource.Region, resource.Name)
+		seen[resourceKey(resource.Type, resource.Region, resource.Name)] = resource
+	}
 	for _, resource := range resources {
-		if resourceExists(resource) {
+		resource.Name = canonicalName(resource.Type, resource.Region, resource.Name)
+		key := resourceKey(resource.Type, resource.Region, resource.Name)
+		found, ok := seen[key]
+		if !ok {
 			result = append(result, resource)
+			seen[key] = resource
+			continue
+		}
+		for _, foundBy := range resource.FoundBy {
+			if !contains(found.FoundBy, foundBy) {
+				found.FoundBy = append(found.FoundBy, foundBy)
+			}
+		}
+		for _, user := range resource.Users {
+			if !contains(found.Users, user) {
+				found.Users = append(found.Users, user)
+			}
+		}
+		if resource.startTime.Before(found.startTime) {
+			found.startTime = resource.startTime
+		}
+		if found.Parent == "" {
+			found.Parent = resource.Parent
+			found.ParentType = resource.ParentType
 		}
 	}
-
 	return result
 }
 
-func searchAllResources(svcCloudtrail *cloudtrail.CloudTrail, username string, starttime time.Time) []*cloudtrail.Resource {
-	v("searchAllResources(", username, ",", starttime, ")")
+// screenResources splits resources into the candidates, neither protected by
+// the policy, nor created before start, nor kept by their handler, the
+// resources protected or kept, and the resources created before start.
+func screenResources(resources []*Resource, start time.Time) (candidates []*Resource, protected []*Resource, preexisting []*Resource) {
+	candidates = resources
+	protected = []*Resource{}
+	if protectionPolicy != nil {
+		candidates, protected = protectionPolicy.protect(candidates)
+	}
+	candidates, preexisting = filterPreexisting(candidates, start)
+	candidates, kept := filterKept(candidates)
+	protected = append(protected, kept...)
+	return candidates, protected, preexisting
+}
+
+// filterOrphans splits resources into the resources kept with their parent,
+// or without one, and the resources whose parent is not among resources, ex:
+// the snapshots of a protected AMI.
+func filterOrphans(resources []*Resource) (kept []*Resource, orphans []*Resource) {
+	kept = []*Resource{}
+	orphans = []*Resource{}
+
+	names := map[string]bool{}
+	for _, resource := range resources {
+		names[resourceKey(resource.Type, resource.Region, resource.Name)] = true
+	}
+	for _, resource := range resources {
+		if resource.Parent != "" && !names[resourceKey(resource.ParentType, resource.Region, resource.Parent)] {
+			v("parent not kept", resource.Type, resource.Name, resource.Parent)
+			orphans = append(orphans, resource)
+			continue
+		}
+		kept = append(kept, resource)
+	}
+	return kept, orphans
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+func searchAllResources(region string, username string, starttime time.Time) []*Resource {
+	v("searchAllResources(", region, ",", username, ",", starttime, ")")
+	svcCloudtrail := cloudtrailClient(region)
 
+	attributeKey, attributeValue := lookupAttribute(username)
 	input := &cloudtrail.LookupEventsInput{
 		StartTime: &starttime,
 		LookupAttributes: []*cloudtrail.LookupAttribute{
 			{
-				AttributeKey:   aws.String("Username"),
-				AttributeValue: &username,
+				AttributeKey:   aws.String(attributeKey),
+				AttributeValue: aws.String(attributeValue),
 			},
 		},
 	}
-	seen := map[string]bool{}
-	resources := []*cloudtrail.Resource{}
+	if !endTime.IsZero() {
+		input.EndTime = aws.Time(endTime)
+	}
+	seen := map[string]*Resource{}
+	resources := []*Resource{}
+
+	state := checkpointScan(region, username, starttime)
+	if state != nil {
+		resources = state.resources()
+		if state.Done {
+			v("searchAllResources(", region, ",", username, ") done in checkpoint, resources", len(resources))
+			return resources
+		}
+		for _, resource := range resources {
+			seen[resourceKey(resource.Type, resource.Region, resource.Name)] = resource
+		}
+		if state.NextToken != "" {
+			v("searchAllResources(", region, ",", username, ") resuming from checkpoint, resources", len(resources))
+			input.NextToken = aws.String(state.NextToken)
+		}
+	}
 
 	retries := 0
 	delay := 1
@@ -192,21 +611,53 @@ LookupLoop:
 				pageNum++
 
 				for _, event := range page.Events {
-					if len(event.Resources) > 0 && IsInterestingEvent(*event.EventName) {
+					if len(event.Resources) > 0 &&
+						IsInterestingEvent(aws.StringValue(event.EventSource), *event.EventName, parseReadOnly(event.ReadOnly)) {
 						for _, resource := range event.Resources {
 							if resource.ResourceType != nil {
-								if !seen[*resource.ResourceName] {
+								resourceRegion := region
+								if isGlobalType(*resource.ResourceType) {
+									resourceRegion = globalRegion
+								}
+								key := resourceKey(*resource.ResourceType, resourceRegion, *resource.ResourceName)
+								if found, ok := seen[key]; !ok {
 									if showevents {
 										v(event)
 									}
-									resources = append(resources, resource)
-									seen[*resource.ResourceName] = true
-									v("└──", *resource.ResourceType, *resource.ResourceName)
+									found = &Resource{
+										Type:      *resource.ResourceType,
+										Name:      *resource.ResourceName,
+										Region:    resourceRegion,
+										EventName: *event.EventName,
+										EventTime: *event.EventTime,
+										EventID:   aws.StringValue(event.EventId),
+										Principal: username,
+										Chain:     []string{username},
+										FoundBy:   []string{backendCloudtrail},
+									}
+									resources = append(resources, found)
+									seen[key] = found
+									v("└──", resourceRegion, *resource.ResourceType, *resource.ResourceName)
+								} else if event.EventTime.Before(found.EventTime) {
+									// Events come newest first, keep the
+									// one that created the resource.
+									found.EventName = *event.EventName
+									found.EventTime = *event.EventTime
+									found.EventID = aws.StringValue(event.EventId)
 								}
 							}
 						}
 					}
 				}
+				// Retries, and resumed runs, continue from the next page
+				input.NextToken = page.NextToken
+				if state != nil {
+					state.update(aws.StringValue(page.NextToken), resources, false)
+					if pageNum%checkpointPages == 0 {
+						saveCheckpoint()
+					}
+				}
+
 				randomDelay := time.Duration(rand.Intn(int(delay))) * time.Second
 				v(
 					"searchAllResources(",
@@ -233,6 +684,13 @@ LookupLoop:
 					randomDelay := time.Duration(rand.Intn(int(delay))) * time.Second
 					v("# Throttled because of too many connections... sleeping", randomDelay, "-- Resources found so far: ", len(resources))
 					if retries >= maxRetries {
+						saveCheckpoint()
+						resumable := "rerun with -checkpoint to make it resumable"
+						if checkpointFile != "" {
+							resumable = "resumable with -checkpoint=" + checkpointFile + " -resume"
+						}
+						logErr.Println("Scan of", username, "in", region, "incomplete after", retries, "throttled retries,", resumable)
+						searchFailed("LookupEvents of "+username+" in "+region, fmt.Errorf("throttled %d times, scan incomplete, %s", retries, resumable))
 						break LookupLoop
 					}
 					time.Sleep(randomDelay)
@@ -246,43 +704,153 @@ LookupLoop:
 
 			logErr.Println("Got error calling LookupEvent:")
 			logErr.Println(err.Error())
-			os.Exit(2)
+			saveCheckpoint()
+			searchFailed("LookupEvents of "+username+" in "+region, err)
+			break LookupLoop
 		} else {
+			if state != nil {
+				state.update("", resources, true)
+				saveCheckpoint()
+			}
 			break LookupLoop
 		}
 	}
 	return resources
 }
 
-func filterInstances(resources []*cloudtrail.Resource) []string {
-	res := []string{}
+// searchRegionCloudtrail returns the resources introduced by principal in
+// the region after start, with LookupEvents. The resources of a role are
+// those of each of its sessions.
+func searchRegionCloudtrail(region string, principal string, start time.Time) []*Resource {
+	if !strings.HasPrefix(principal, assumedRolePrefix) {
+		return searchAllResources(region, principal, start)
+	}
 
-	for _, resource := range resources {
-		if (*resource.ResourceName)[:2] == "i-" &&
-			*resource.ResourceType == "AWS::EC2::Instance" {
-			res = append(res, *resource.ResourceName)
+	resources := []*Resource{}
+	role := strings.TrimPrefix(principal, assumedRolePrefix)
+	for _, session := range roleSessions(region, role, start) {
+		resources = mergeResources(resources, searchAllResources(region, session, start))
+	}
+	return resources
+}
+
+// searchCloudtrail returns the resources introduced by principal in the
+// regions after start, from the log files of -trail or from LookupEvents.
+// The events of IAM and the other global services are only logged in
+// us-east-1, which is searched for them when it is not in regions.
+func searchCloudtrail(regions []string, principal string, start time.Time) []*Resource {
+	if trailLocation != "" {
+		return searchTrailResources(regions, principal, start)
+	}
+
+	resources := []*Resource{}
+	for _, region := range regions {
+		resources = mergeResources(resources, searchRegionCloudtrail(region, principal, start))
+	}
+
+	if !contains(regions, globalEventsRegion) {
+		global := []*Resource{}
+		for _, resource := range searchRegionCloudtrail(globalEventsRegion, principal, start) {
+			if resource.Region == globalRegion {
+				global = append(global, resource)
+			}
 		}
+		resources = mergeResources(resources, global)
 	}
-	return res
+	return resources
 }
 
 func main() {
 	parseFlags()
 
+	// Keep stdout for the report when it is a structured document, and for
+	// the -emit document
+	var logWriter io.Writer = os.Stdout
+	if outputFormat != "text" || emitFormat != "" {
+		logWriter = os.Stderr
+	}
+	var reportWriter io.Writer = os.Stdout
+	if emitFormat != "" {
+		reportWriter = os.Stderr
+	}
+
 	logErr = log.New(os.Stderr, "!!! ", log.LstdFlags)
 	if quietmode {
 		logOut = log.New(ioutil.Discard, "    ", log.LstdFlags)
 	} else {
-		logOut = log.New(os.Stdout, "    ", log.LstdFlags)
+		logOut = log.New(logWriter, "    ", log.LstdFlags)
 	}
 	if debug {
-		logDebug = log.New(os.Stdout, "(d) ", log.LstdFlags)
+		logDebug = log.New(logWriter, "(d) ", log.LstdFlags)
 	} else {
 		logDebug = log.New(ioutil.Discard, "(d) ", log.LstdFlags)
 	}
-	logReport = log.New(os.Stdout, "+++ ", log.LstdFlags)
+	logReport = log.New(reportWriter, "+++ ", log.LstdFlags)
 
 	var err error
+	if policyFile != "" {
+		protectionPolicy, err = loadPolicy(policyFile)
+		if err != nil {
+			logErr.Println("Error loading policy", policyFile)
+			logErr.Println(err.Error())
+			os.Exit(1)
+		}
+	}
+
+	eventRules, err = compileEventRules(eventRulesFile)
+	if err != nil {
+		logErr.Println("Error loading event rules", eventRulesFile)
+		logErr.Println(err.Error())
+		os.Exit(1)
+	}
+
+	if pricesFile != "" {
+		prices, err = loadPrices(pricesFile)
+		if err != nil {
+			logErr.Println("Error loading prices", pricesFile)
+			logErr.Println(err.Error())
+			os.Exit(1)
+		}
+	}
+
+	users := []*userStart{}
+	if userName != "" || len(lookups) > 0 {
+		principals := lookups
+		if userName != "" {
+			principals = append([]string{userName}, lookups...)
+		}
+		users = append(users, &userStart{
+			User:       userName,
+			StartTime:  startTime,
+			Principals: principals,
+		})
+	}
+	if usersFile != "" {
+		fileUsers, err := loadUsers(usersFile)
+		if err != nil {
+			logErr.Println("Error loading users", usersFile)
+			logErr.Println(err.Error())
+			os.Exit(1)
+		}
+		users = append(users, fileUsers...)
+	}
+
+	// The checkpoint of a -users run is for the file
+	checkpointUser := userName
+	if usersFile != "" {
+		checkpointUser = usersFile
+	}
+	if resume {
+		checkpointState, err = loadCheckpoint(checkpointFile, checkpointUser, startTime)
+		if err != nil {
+			logErr.Println("Error loading checkpoint", checkpointFile)
+			logErr.Println(err.Error())
+			os.Exit(1)
+		}
+	} else if checkpointFile != "" {
+		checkpointState = newCheckpoint(checkpointUser, startTime)
+	}
+
 	sess, err = session.NewSession(
 		&aws.Config{
 			Region:     aws.String(os.Getenv("AWS_REGION")),
@@ -295,29 +863,265 @@ func main() {
 		logErr.Println(err.Error())
 		os.Exit(1)
 	}
+	installRateLimiter(sess)
+	baseSession = sess
+	defaultRegion = aws.StringValue(sess.Config.Region)
+
+	targets, err := accountTargets()
+	if err != nil {
+		logErr.Println("Got error listing accounts:")
+		logErr.Println(err.Error())
+		os.Exit(1)
+	}
+
+	reportID = newReportID()
+	reports := []*Report{}
+	for _, target := range targets {
+		if err := useAccount(target); err != nil {
+			logErr.Println("Got error using account", target.Account)
+			logErr.Println(err.Error())
+			reports = append(reports, errorReport([]string{}, err.Error()))
+			continue
+		}
+		reports = append(reports, auditAccount(users))
+	}
+
+	report := reports[0]
+	if len(reports) > 1 {
+		report = &Report{
+			ID:        reportID,
+			User:      userName,
+			StartTime: startTime,
+			Regions:   []string{},
+			Resources: []*Resource{},
+			Cost:      &Cost{},
+			Accounts:  reports,
+		}
+		for _, accountReport := range reports {
+			report.Cost.Hourly += accountReport.Cost.Hourly
+			report.Cost.Monthly += accountReport.Cost.Monthly
+			report.BelowMinCost += accountReport.BelowMinCost
+		}
+	}
 
-	svcCloudtrail = cloudtrail.New(sess)
+	if outputFormat != "text" {
+		if err := writeReport(os.Stdout, report, outputFormat); err != nil {
+			logErr.Println("Got error writing report:")
+			logErr.Println(err.Error())
+			os.Exit(1)
+		}
+	}
 
-	resources := searchAllResources(svcCloudtrail, userName, startTime)
+	if emitFormat == "script" {
+		if err := writeScript(os.Stdout, reports); err != nil {
+			logErr.Println("Got error writing deletion script:")
+			logErr.Println(err.Error())
+			os.Exit(1)
+		}
+	}
+	if emitFormat == "aws-nuke" {
+		blocklist := []string{}
+		for _, account := range strings.Split(nukeBlocklist, ",") {
+			if account = strings.TrimSpace(account); account != "" {
+				blocklist = append(blocklist, account)
+			}
+		}
+		if err := writeNukeConfig(os.Stdout, reports, blocklist); err != nil {
+			logErr.Println("Got error writing aws-nuke configuration:")
+			logErr.Println(err.Error())
+			os.Exit(1)
+		}
+	}
 
-	if recursive {
-		for _, instance := range filterInstances(resources) {
-			resources = append(resources, searchAllResources(svcCloudtrail, instance, startTime)...)
+	status := 0
+	for _, report := range reports {
+		switch {
+		case report.Error != "":
+			status = 5
+		case status == 5:
+		case len(report.NotDeleted) > 0 || (quarantineMode && len(report.NotQuarantined) > 0):
+			status = 3
+		case len(report.Unverified) > 0 && status == 0:
+			status = 4
+		}
+	}
+	os.Exit(status)
+}
+
+// errorReport returns the report of the current account when it could not be
+// audited, ex: its role cannot be assumed.
+func errorReport(regions []string, message string) *Report {
+	report := &Report{
+		ID:        reportID,
+		Account:   currentAccount,
+		RoleArn:   currentRoleArn,
+		User:      userName,
+		StartTime: startTime,
+		Regions:   regions,
+		Resources: []*Resource{},
+		Cost:      &Cost{},
+		Error:     message,
+	}
+	if outputFormat == "text" {
+		printTextReport(report)
+	}
+	return report
+}
+
+// auditAccount searches, checks and, with -delete, deletes the resources of
+// the users in the current account.
+func auditAccount(users []*userStart) *Report {
+	regions, err := searchRegions()
+	if err != nil {
+		logErr.Println("Got error calling DescribeRegions:")
+		logErr.Println(err.Error())
+		return errorReport([]string{}, err.Error())
+	}
+	v("Regions:", strings.Join(regions, ", "))
+
+	if backend != backendTags && trailLocation != "" {
+		// The archive is read once for all the users and their principals
+		start := startTime
+		for _, user := range users {
+			if start.IsZero() || user.StartTime.Before(start) {
+				start = user.StartTime
+			}
+		}
+		if err := loadTrail(trailLocation, start, endTime); err != nil {
+			logErr.Println("Got error reading CloudTrail logs from", trailLocation)
+			logErr.Println(err.Error())
+			return errorReport(regions, err.Error())
 		}
 	}
 
+	resources := []*Resource{}
+	if backend != backendTags {
+		resources = searchUsers(regions, users)
+	}
+	if backend != backendCloudtrail {
+		tagged := []*Resource{}
+		for _, region := range regions {
+			tagged = mergeResources(tagged, searchTaggedResources(region, tags))
+		}
+		if recursive {
+			tagged = searchRecursive(regions, tagged, depth, []string{}, startTime)
+		}
+		resources = mergeResources(resources, tagged)
+	}
+
 	v("Total number of resources to test for existence:", len(resources))
-	existingResources := filterExisting(resources)
+	existingResources, unverified, unsupported := filterExisting(resources)
 
-	if len(existingResources) > 0 {
-		logReport.Println("Activity of user", userName, "starting at ", startTime)
-		logReport.Println("Number of resources still existing:", len(existingResources))
-		logReport.Println()
-		for _, resource := range existingResources {
-			logReport.Println(*resource.ResourceType, *resource.ResourceName)
+	// addExisting returns the resources found from the existing ones that
+	// exist
+	addExisting := func(what string, found []*Resource) []*Resource {
+		before := len(resources)
+		resources = mergeResources(resources, found)
+		v("Number of", what, "to test for existence:", len(resources)-before)
+		foundExisting, foundUnverified, foundUnsupported := filterExisting(resources[before:])
+		unverified = append(unverified, foundUnverified...)
+		for resourceType, count := range foundUnsupported {
+			unsupported[resourceType] += count
 		}
-	} else {
-		logOut.Println("Activity of user", userName, "starting at ", startTime)
-		logOut.Println("No resources found.")
+		return foundExisting
+	}
+
+	protected := []*Resource{}
+	preexisting := []*Resource{}
+	screen := func(candidates []*Resource) []*Resource {
+		candidates, moreProtected, morePreexisting := screenResources(candidates, startTime)
+		protected = append(protected, moreProtected...)
+		preexisting = append(preexisting, morePreexisting...)
+		return candidates
+	}
+
+	// The AMIs are screened with their snapshots below
+	screened := screen(existingResources)
+	if clusters {
+		// The infra ids of the clusters are in the tags of their resources
+		found := addExisting("resources of the clusters", searchClusters(regions, screened))
+		screened = append(screened, screen(found)...)
+		existingResources = append(existingResources, found...)
+	}
+	// The snapshots of a protected or preexisting AMI are orphans
+	found := addExisting("snapshots of the AMIs", searchImageSnapshots(existingResources))
+	existingResources = append(screened, screen(found)...)
+
+	if message := searchError(); message != "" {
+		// The resources left out could depend on those found
+		logErr.Println("The search of account", currentAccount, "is incomplete, nothing is deleted or quarantined")
+		return errorReport(regions, message)
+	}
+
+	existingResources, orphans := filterOrphans(existingResources)
+	for _, orphan := range orphans {
+		orphan.ProtectedBy = orphan.Parent
+	}
+	protected = append(protected, orphans...)
+	cheap, cost := estimateCosts(existingResources, minCost)
+
+	// Quarantine tags are in the details, act before they are dropped
+	inGracePeriod := []*Resource{}
+	notQuarantined := []*Resource{}
+	if reapMode {
+		existingResources, inGracePeriod, notQuarantined = filterQuarantined(existingResources, gracePeriod)
+		// Children are reaped with their parent
+		existingResources, orphans = filterOrphans(existingResources)
+		inGracePeriod = append(inGracePeriod, orphans...)
+	}
+	if quarantineMode && len(existingResources) > 0 {
+		notQuarantined = quarantineResources(existingResources, reportID)
+	}
+
+	if !showDetails {
+		// Details were only needed to match tags, creation times and costs
+		for _, resources := range [][]*Resource{existingResources, protected, preexisting, inGracePeriod, notQuarantined} {
+			for _, resource := range resources {
+				resource.Details = nil
+			}
+		}
+	}
+
+	report := &Report{
+		ID:             reportID,
+		Account:        currentAccount,
+		RoleArn:        currentRoleArn,
+		User:           userName,
+		Lookups:        lookups,
+		Tags:           tags,
+		StartTime:      startTime,
+		Regions:        regions,
+		Resources:      existingResources,
+		Unverified:     unverified,
+		Protected:      protected,
+		Preexisting:    preexisting,
+		Provenance:     provenanceTree(existingResources),
+		Unsupported:    unsupported,
+		Cost:           cost,
+		BelowMinCost:   cheap,
+		InGracePeriod:  inGracePeriod,
+		NotQuarantined: notQuarantined,
+		// Events of this account only, counts are reset for the next one
+		FilteredEvents: resetEventRuleCounts(),
 	}
+	if usersFile != "" {
+		report.Users = users
+		report.ByUser = resourcesByUser(existingResources)
+	}
+	if quarantineMode {
+		report.Quarantined = len(existingResources) - len(notQuarantined)
+	}
+	if outputFormat == "text" {
+		printTextReport(report)
+	}
+
+	if (deleteMode || reapMode) && len(existingResources) > 0 {
+		report.NotDeleted = deleteResources(existingResources)
+		report.Deleted = len(existingResources) - len(report.NotDeleted)
+		if outputFormat == "text" {
+			printTextDeleteReport(report)
+		}
+	}
+
+	return report
 }
@@ -0,0 +1,58 @@
+package main
+
+import (
+	"io/ioutil"
+	"log"
+	"os"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestMain(m *testing.M) {
+	logErr = log.New(ioutil.Discard, "!!! ", log.LstdFlags)
+	logOut = log.New(ioutil.Discard, "    ", log.LstdFlags)
+	logDebug = log.New(ioutil.Discard, "(d) ", log.LstdFlags)
+	logReport = log.New(ioutil.Discard, "+++ ", log.LstdFlags)
+	os.Exit(m.Run())
+}
+
+func TestMergeResources(t *testing.T) {
+	start := time.Date(2019, 1, 14, 9, 4, 25, 0, time.UTC)
+	result := mergeResources(nil, []*Resource{
+		{Type: "AWS::IAM::Role", Name: "arn:aws:iam::123456789012:role/web", Region: globalRegion, FoundBy: []string{backendCloudtrail}, Users: []string{"alice"}, startTime: start},
+		{Type: "AWS::IAM::InstanceProfile", Name: "web", Region: globalRegion, FoundBy: []string{backendCloudtrail}},
+		{Type: "AWS::S3::Bucket", Name: "web", Region: globalRegion, FoundBy: []string{backendCloudtrail}},
+	})
+	result = mergeResources(result, []*Resource{
+		{Type: "AWS::IAM::Role", Name: "web", Region: globalRegion, FoundBy: []string{backendTags}, Users: []string{"bob"}, startTime: start.Add(-time.Hour)},
+		{Type: "AWS::EC2::Instance", Name: "i-0123", Region: "us-east-1"},
+		{Type: "AWS::EC2::Instance", Name: "i-0123", Region: "eu-west-1"},
+	})
+
+	var names []string
+	for _, resource := range result {
+		names = append(names, resource.Type+" "+resource.Region+" "+resource.Name)
+	}
+	want := []string{
+		"AWS::IAM::Role global web",
+		"AWS::IAM::InstanceProfile global web",
+		"AWS::S3::Bucket global web",
+		"AWS::EC2::Instance us-east-1 i-0123",
+		"AWS::EC2::Instance eu-west-1 i-0123",
+	}
+	if !reflect.DeepEqual(names, want) {
+		t.Fatalf("got %q, want %q", names, want)
+	}
+
+	role := result[0]
+	if !reflect.DeepEqual(role.FoundBy, []string{backendCloudtrail, backendTags}) {
+		t.Errorf("role found by %q", role.FoundBy)
+	}
+	if !reflect.DeepEqual(role.Users, []string{"alice", "bob"}) {
+		t.Errorf("role users %q", role.Users)
+	}
+	if !role.startTime.Equal(start.Add(-time.Hour)) {
+		t.Errorf("role start time %v, want the earliest", role.startTime)
+	}
+}
@@ -0,0 +1,186 @@
+package main
+
+import (
+	"errors"
+	"gopkg.in/yaml.v2"
+	"io"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// nukeConfig is an aws-nuke configuration, see
+// https://github.com/rebuy-de/aws-nuke#usage
+type nukeConfig struct {
+	Regions          []string                `yaml:"regions"`
+	AccountBlocklist []string                `yaml:"account-blocklist"`
+	ResourceTypes    nukeTypes               `yaml:"resource-types"`
+	Accounts         map[string]*nukeAccount `yaml:"accounts"`
+}
+
+type nukeTypes struct {
+	Targets []string `yaml:"targets"`
+}
+
+type nukeAccount struct {
+	Filters map[string][]*nukeFilter `yaml:"filters"`
+}
+
+// nukeFilter excludes resources from aws-nuke. Inverted, it excludes the
+// resources not matching Value.
+type nukeFilter struct {
+	Type   string `yaml:"type"`
+	Value  string `yaml:"value"`
+	Invert bool   `yaml:"invert"`
+}
+
+// nukeID returns the id aws-nuke knows a resource by: ELBv2 load balancers
+// and target groups by name, record sets by name, others by the id janitor
+// found.
+func nukeID(resource *Resource) string {
+	switch resource.Type {
+	case "AWS::Route53::RecordSet":
+		if _, _, name, err := parseRoute53RecordSetName(resource.Name); err == nil {
+			return name
+		}
+	case "AWS::ElasticLoadBalancingV2::LoadBalancer", "AWS::ElasticLoadBalancingV2::TargetGroup":
+		// arn:aws:elasticloadbalancing:region:account:loadbalancer/app/name/id
+		if strings.HasPrefix(resource.Name, "arn:") {
+			parts := strings.Split(resource.Name, "/")
+			if len(parts) >= 3 {
+				return parts[len(parts)-2]
+			}
+		}
+	}
+	return resource.Name
+}
+
+// nukeRegion returns the region aws-nuke lists a resource in: the region of
+// the bucket for S3 buckets, which janitor reports in the global region.
+func nukeRegion(resource *Resource) (string, error) {
+	if resource.Type == "AWS::S3::Bucket" {
+		return s3BucketRegion(resource.Name)
+	}
+	return resource.Region, nil
+}
+
+// nukeConfigsOf returns the aws-nuke configurations deleting exactly the
+// resources of the reports, one per region, one account per report. aws-nuke
+// deletes all the resources of its target types but those matching a filter,
+// so every target type is filtered in every account by an inverted regular
+// expression of the ids of the resources to delete. Ids also match at the end
+// of an ARN or a path, ex: IAM policy ARNs, hosted zone paths. Filters are per
+// account, not per region, and names are only unique in a region, ex: key
+// pairs, load balancers, so each configuration has a single region.
+func nukeConfigsOf(reports []*Report, blocklist []string) ([]*nukeConfig, error) {
+	// Ids by region, account and aws-nuke type
+	ids := map[string]map[string]map[string][]string{}
+	unsupported := map[string]int{}
+
+	for _, report := range reports {
+		account := report.Account
+		if account == "" {
+			var err error
+			if account, err = callerAccount(); err != nil {
+				return nil, err
+			}
+		}
+
+		for _, resource := range report.Resources {
+			handler, ok := resourceHandlers[resource.Type]
+			nukeType := ""
+			if ok {
+				nukeType = handler.NukeType
+			}
+			if nukeType == "" {
+				unsupported[resource.Type]++
+				continue
+			}
+			region, err := nukeRegion(resource)
+			if err != nil {
+				return nil, err
+			}
+			if ids[region] == nil {
+				ids[region] = map[string]map[string][]string{}
+			}
+			if ids[region][account] == nil {
+				ids[region][account] = map[string][]string{}
+			}
+			ids[region][account][nukeType] = append(ids[region][account][nukeType], regexp.QuoteMeta(nukeID(resource)))
+		}
+	}
+
+	for resourceType, count := range unsupported {
+		logErr.Println("Type", resourceType, "has no aws-nuke resource type,", count, "resources not in the configuration")
+	}
+
+	// Without targets aws-nuke would delete every type
+	if len(ids) == 0 {
+		return nil, errors.New("no resources to delete, no configuration written")
+	}
+
+	regions := []string{}
+	for region := range ids {
+		regions = append(regions, region)
+	}
+	sort.Strings(regions)
+
+	configs := []*nukeConfig{}
+	for _, region := range regions {
+		config := &nukeConfig{
+			Regions:          []string{region},
+			AccountBlocklist: blocklist,
+			ResourceTypes:    nukeTypes{Targets: []string{}},
+			Accounts:         map[string]*nukeAccount{},
+		}
+
+		targets := map[string]bool{}
+		for _, accountIds := range ids[region] {
+			for target := range accountIds {
+				targets[target] = true
+			}
+		}
+		for target := range targets {
+			config.ResourceTypes.Targets = append(config.ResourceTypes.Targets, target)
+		}
+		sort.Strings(config.ResourceTypes.Targets)
+
+		for account, accountIds := range ids[region] {
+			filters := map[string][]*nukeFilter{}
+			for _, target := range config.ResourceTypes.Targets {
+				// ^$ matches no resource, the type is not deleted in this account
+				value := "^$"
+				if names := accountIds[target]; len(names) > 0 {
+					sort.Strings(names)
+					value = "^(.*[/:])?(" + strings.Join(names, "|") + ")$"
+				}
+				filters[target] = []*nukeFilter{{Type: "regex", Value: value, Invert: true}}
+			}
+			config.Accounts[account] = &nukeAccount{Filters: filters}
+		}
+		configs = append(configs, config)
+	}
+	return configs, nil
+}
+
+// writeNukeConfig writes the aws-nuke configurations of the reports, as YAML
+// documents separated by ---, one per region.
+func writeNukeConfig(w io.Writer, reports []*Report, blocklist []string) error {
+	configs, err := nukeConfigsOf(reports, blocklist)
+	if err != nil {
+		return err
+	}
+	for i, config := range configs {
+		out, err := yaml.Marshal(config)
+		if err != nil {
+			return err
+		}
+		if i > 0 {
+			out = append([]byte("---\n"), out...)
+		}
+		if _, err = w.Write(out); err != nil {
+			return err
+		}
+	}
+	return nil
+}
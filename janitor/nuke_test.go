@@ -0,0 +1,130 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestNukeID(t *testing.T) {
+	tests := []struct {
+		resource *Resource
+		want     string
+	}{
+		{&Resource{Type: "AWS::EC2::Instance", Name: "i-0123"}, "i-0123"},
+		{&Resource{Type: "AWS::ElasticLoadBalancingV2::LoadBalancer", Name: "arn:aws:elasticloadbalancing:us-east-1:123456789012:loadbalancer/app/web/0123"}, "web"},
+		{&Resource{Type: "AWS::ElasticLoadBalancingV2::TargetGroup", Name: "arn:aws:elasticloadbalancing:us-east-1:123456789012:targetgroup/web/0123"}, "web"},
+		{&Resource{Type: "AWS::Route53::RecordSet", Name: "Z0123:A:api.cluster.example.com."}, "api.cluster.example.com."},
+	}
+
+	for _, test := range tests {
+		if got := nukeID(test.resource); got != test.want {
+			t.Errorf("nukeID(%s) = %s, want %s", test.resource.Name, got, test.want)
+		}
+	}
+}
+
+// nukeFilters returns the filter values of each region, account and type of
+// configs.
+func nukeFilters(configs []*nukeConfig) map[string]map[string]map[string]string {
+	result := map[string]map[string]map[string]string{}
+	for _, config := range configs {
+		region := config.Regions[0]
+		result[region] = map[string]map[string]string{}
+		for account, accountConfig := range config.Accounts {
+			result[region][account] = map[string]string{}
+			for target, filters := range accountConfig.Filters {
+				result[region][account][target] = filters[0].Value
+			}
+		}
+	}
+	return result
+}
+
+func TestNukeConfigsOf(t *testing.T) {
+	tests := []struct {
+		name    string
+		reports []*Report
+		want    map[string]map[string]map[string]string
+		wantErr bool
+	}{
+		{
+			name: "names of a region only",
+			reports: []*Report{{Account: "123456789012", Resources: []*Resource{
+				{Type: "AWS::EC2::KeyPair", Name: "key", Region: "us-east-1"},
+				{Type: "AWS::EC2::Instance", Name: "i-0123", Region: "eu-west-1"},
+				{Type: "AWS::IAM::Role", Name: "role", Region: globalRegion},
+			}}},
+			want: map[string]map[string]map[string]string{
+				"us-east-1":  {"123456789012": {"EC2KeyPair": "^(.*[/:])?(key)$"}},
+				"eu-west-1":  {"123456789012": {"EC2Instance": "^(.*[/:])?(i-0123)$"}},
+				globalRegion: {"123456789012": {"IAMRole": "^(.*[/:])?(role)$"}},
+			},
+		},
+		{
+			name: "accounts without resources of a type delete none",
+			reports: []*Report{
+				{Account: "123456789012", Resources: []*Resource{
+					{Type: "AWS::EC2::Instance", Name: "i-1", Region: "us-east-1"},
+					{Type: "AWS::EC2::Instance", Name: "i-0", Region: "us-east-1"},
+				}},
+				{Account: "210987654321", Resources: []*Resource{
+					{Type: "AWS::EC2::Volume", Name: "vol-0", Region: "us-east-1"},
+				}},
+			},
+			want: map[string]map[string]map[string]string{
+				"us-east-1": {
+					"123456789012": {"EC2Instance": "^(.*[/:])?(i-0|i-1)$", "EC2Volume": "^$"},
+					"210987654321": {"EC2Instance": "^$", "EC2Volume": "^(.*[/:])?(vol-0)$"},
+				},
+			},
+		},
+		{
+			name: "ids are quoted, types without aws-nuke type left out",
+			reports: []*Report{{Account: "123456789012", Resources: []*Resource{
+				{Type: "AWS::Route53::RecordSet", Name: "Z0123:A:*.apps.example.com.", Region: globalRegion},
+				{Type: "AWS::ElasticLoadBalancingV2::Listener", Name: "arn:listener", Region: "us-east-1"},
+			}}},
+			want: map[string]map[string]map[string]string{
+				globalRegion: {"123456789012": {"Route53ResourceRecordSet": `^(.*[/:])?(\*\.apps\.example\.com\.)$`}},
+			},
+		},
+		{
+			name: "nothing to delete",
+			reports: []*Report{{Account: "123456789012", Resources: []*Resource{
+				{Type: "AWS::ElasticLoadBalancingV2::Listener", Name: "arn:listener", Region: "us-east-1"},
+			}}},
+			wantErr: true,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			configs, err := nukeConfigsOf(test.reports, []string{"999999999999"})
+			if (err != nil) != test.wantErr {
+				t.Fatalf("nukeConfigsOf() error = %v, want error %v", err, test.wantErr)
+			}
+			if test.wantErr {
+				return
+			}
+
+			for _, config := range configs {
+				if len(config.Regions) != 1 {
+					t.Errorf("regions %v, want a single one", config.Regions)
+				}
+				if !reflect.DeepEqual(config.AccountBlocklist, []string{"999999999999"}) {
+					t.Errorf("blocklist %v", config.AccountBlocklist)
+				}
+				for _, filters := range config.Accounts {
+					for _, filter := range filters.Filters {
+						if len(filter) != 1 || filter[0].Type != "regex" || !filter[0].Invert {
+							t.Errorf("filters %v, want a single inverted regex", filter)
+						}
+					}
+				}
+			}
+			if got := nukeFilters(configs); !reflect.DeepEqual(got, test.want) {
+				t.Errorf("filters = %v, want %v", got, test.want)
+			}
+		})
+	}
+}
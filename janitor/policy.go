@@ -0,0 +1,169 @@
+package main
+
+import (
+	"fmt"
+	"gopkg.in/yaml.v2"
+	"io/ioutil"
+	"regexp"
+	"strconv"
+)
+
+// policy is the protection policy loaded from the -policy file.
+//
+// Rules are evaluated in order, the first matching rule wins: a resource
+// matching a deny rule is protected, it is neither reported as existing nor
+// deleted. A resource matching an allow rule, or no rule, is a candidate.
+type policy struct {
+	Rules []*policyRule `yaml:"rules"`
+}
+
+// policyRule matches resources by type, id or name, tags, account and
+// region. Every condition set must match. Type, id and tag values are
+// regular expressions matching whole values, see compilePolicyRegexp.
+type policyRule struct {
+	Name    string            `yaml:"name"`
+	Action  string            `yaml:"action"`
+	Type    string            `yaml:"type"`
+	ID      string            `yaml:"id"`
+	Tags    map[string]string `yaml:"tags"`
+	Account string            `yaml:"account"`
+	Region  string            `yaml:"region"`
+
+	typeRegexp *regexp.Regexp
+	idRegexp   *regexp.Regexp
+	tagRegexps map[string]*regexp.Regexp
+}
+
+var protectionPolicy *policy
+
+// loadPolicy reads and validates a policy file.
+func loadPolicy(path string) (*policy, error) {
+	content, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	p := &policy{}
+	if err := yaml.UnmarshalStrict(content, p); err != nil {
+		return nil, err
+	}
+
+	for i, rule := range p.Rules {
+		if rule.Name == "" {
+			rule.Name = "rule " + strconv.Itoa(i+1)
+		}
+		if rule.Action != "allow" && rule.Action != "deny" {
+			return nil, fmt.Errorf("%s: action must be allow or deny", rule.Name)
+		}
+		if rule.typeRegexp, err = compilePolicyRegexp(rule.Type); err != nil {
+			return nil, fmt.Errorf("%s: type: %s", rule.Name, err)
+		}
+		if rule.idRegexp, err = compilePolicyRegexp(rule.ID); err != nil {
+			return nil, fmt.Errorf("%s: id: %s", rule.Name, err)
+		}
+		rule.tagRegexps = map[string]*regexp.Regexp{}
+		for key, value := range rule.Tags {
+			if rule.tagRegexps[key], err = compilePolicyRegexp(value); err != nil {
+				return nil, fmt.Errorf("%s: tag %s: %s", rule.Name, key, err)
+			}
+		}
+	}
+
+	return p, nil
+}
+
+// compilePolicyRegexp compiles an expression of a rule anchored at both ends,
+// so that id: vpc-1 does not match vpc-12345. An empty expression matches
+// anything, ex: a tag whatever its value.
+func compilePolicyRegexp(expr string) (*regexp.Regexp, error) {
+	if expr == "" {
+		expr = ".*"
+	}
+	return regexp.Compile("^(?:" + expr + ")$")
+}
+
+// needsTags returns true if some rules match on tags, the resources must
+// then be described before the policy is applied.
+func (p *policy) needsTags() bool {
+	for _, rule := range p.Rules {
+		if len(rule.Tags) > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// matches returns true if the rule matches the resource. When the tags of the
+// resource or the account are unknown, a deny rule matches, to stay on the
+// safe side.
+func (rule *policyRule) matches(resource *Resource, account string) bool {
+	if !rule.typeRegexp.MatchString(resource.Type) ||
+		!rule.idRegexp.MatchString(resource.Name) {
+		return false
+	}
+	if rule.Region != "" && rule.Region != resource.Region {
+		return false
+	}
+	if rule.Account != "" && account == "" {
+		return rule.Action == "deny"
+	}
+	if rule.Account != "" && rule.Account != account {
+		return false
+	}
+
+	if len(rule.tagRegexps) == 0 {
+		return true
+	}
+	if resource.Details == nil {
+		return rule.Action == "deny"
+	}
+	for key, valueRegexp := range rule.tagRegexps {
+		value, ok := resource.Details.Tags[key]
+		if !ok || !valueRegexp.MatchString(value) {
+			return false
+		}
+	}
+	return true
+}
+
+// protect splits resources into the candidates and the resources protected by
+// a deny rule. ProtectedBy is set to the name of the rule.
+func (p *policy) protect(resources []*Resource) (candidates []*Resource, protected []*Resource) {
+	candidates = []*Resource{}
+	protected = []*Resource{}
+
+	account := ""
+	for _, rule := range p.Rules {
+		if rule.Account != "" {
+			var err error
+			if account, err = callerAccount(); err != nil {
+				logErr.Println("Got error calling GetCallerIdentity:")
+				logErr.Println(err.Error())
+			}
+			break
+		}
+	}
+
+	if p.needsTags() {
+		describeResources(resources)
+	}
+
+ResourceLoop:
+	for _, resource := range resources {
+		for _, rule := range p.Rules {
+			if !rule.matches(resource, account) {
+				continue
+			}
+			if rule.Action == "deny" {
+				v("protected", resource.Type, resource.Name, "by", rule.Name)
+				resource.ProtectedBy = rule.Name
+				protected = append(protected, resource)
+				continue ResourceLoop
+			}
+			break
+		}
+		candidates = append(candidates, resource)
+	}
+
+	return candidates, protected
+}
@@ -0,0 +1,160 @@
+package main
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+)
+
+// testPolicy loads a policy from its YAML content.
+func testPolicy(t *testing.T, content string) (*policy, error) {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "policy.yaml")
+	if err := ioutil.WriteFile(path, []byte(content), 0600); err != nil {
+		t.Fatal(err)
+	}
+	return loadPolicy(path)
+}
+
+func TestLoadPolicy(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		wantErr bool
+	}{
+		{
+			name:    "valid",
+			content: "rules:\n- action: deny\n  type: '^AWS::IAM::'\n- action: allow\n",
+		},
+		{
+			name:    "missing action",
+			content: "rules:\n- type: '^AWS::IAM::'\n",
+			wantErr: true,
+		},
+		{
+			name:    "unknown action",
+			content: "rules:\n- action: delete\n",
+			wantErr: true,
+		},
+		{
+			name:    "invalid id",
+			content: "rules:\n- action: deny\n  id: '('\n",
+			wantErr: true,
+		},
+		{
+			name:    "invalid tag",
+			content: "rules:\n- action: deny\n  tags:\n    owner: '['\n",
+			wantErr: true,
+		},
+		{
+			name:    "unknown field",
+			content: "rules:\n- action: deny\n  kind: instance\n",
+			wantErr: true,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			_, err := testPolicy(t, test.content)
+			if (err != nil) != test.wantErr {
+				t.Errorf("loadPolicy() error = %v, want error %v", err, test.wantErr)
+			}
+		})
+	}
+}
+
+func TestPolicyRuleMatches(t *testing.T) {
+	instance := &Resource{
+		Type:    "AWS::EC2::Instance",
+		Name:    "i-0123",
+		Region:  "us-east-1",
+		Details: &ResourceDetails{Tags: map[string]string{"env": "prod-1"}},
+	}
+	undescribed := &Resource{Type: "AWS::EC2::Instance", Name: "i-4567", Region: "us-east-1"}
+
+	tests := []struct {
+		name     string
+		rule     string
+		resource *Resource
+		account  string
+		want     bool
+	}{
+		{"empty rule", "action: allow", instance, "", true},
+		{"type", "action: deny\n  type: 'AWS::EC2::.*'", instance, "", true},
+		{"other type", "action: deny\n  type: 'AWS::IAM::.*'", instance, "", false},
+		{"anchored type", "action: deny\n  type: 'AWS::EC2::'", instance, "", false},
+		{"id", "action: deny\n  id: i-0123", instance, "", true},
+		{"anchored id", "action: deny\n  id: '0123'", instance, "", false},
+		{"id prefix", "action: deny\n  id: i-01", instance, "", false},
+		{"anchors kept", "action: deny\n  id: ^i-0123$", instance, "", true},
+		{"alternatives anchored", "action: deny\n  id: i-9|i-0123", instance, "", true},
+		{"region", "action: deny\n  region: us-east-1", instance, "", true},
+		{"other region", "action: deny\n  region: eu-west-1", instance, "", false},
+		{"account", "action: deny\n  account: '123456789012'", instance, "123456789012", true},
+		{"other account", "action: deny\n  account: '123456789012'", instance, "210987654321", false},
+		{"unknown account denies", "action: deny\n  account: '123456789012'", instance, "", true},
+		{"unknown account does not allow", "action: allow\n  account: '123456789012'", instance, "", false},
+		{"tag", "action: deny\n  tags:\n    env: prod-.*", instance, "", true},
+		{"anchored tag value", "action: deny\n  tags:\n    env: prod", instance, "", false},
+		{"any tag value", "action: deny\n  tags:\n    env: ''", instance, "", true},
+		{"other tag value", "action: deny\n  tags:\n    env: dev-.*", instance, "", false},
+		{"missing tag", "action: deny\n  tags:\n    owner: .", instance, "", false},
+		{"unknown tags deny", "action: deny\n  tags:\n    env: prod-.*", undescribed, "", true},
+		{"unknown tags do not allow", "action: allow\n  tags:\n    env: prod-.*", undescribed, "", false},
+		{"every condition", "action: deny\n  type: AWS::EC2::Instance\n  region: eu-west-1\n  tags:\n    env: prod-.*", instance, "", false},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			p, err := testPolicy(t, "rules:\n- "+test.rule+"\n")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := p.Rules[0].matches(test.resource, test.account); got != test.want {
+				t.Errorf("matches() = %v, want %v", got, test.want)
+			}
+		})
+	}
+}
+
+func TestPolicyProtect(t *testing.T) {
+	p, err := testPolicy(t, `rules:
+- name: keep the prod key
+  action: allow
+  type: AWS::EC2::KeyPair
+  id: prod-ci
+- name: keys
+  action: deny
+  type: AWS::EC2::KeyPair
+- name: tagged
+  action: deny
+  tags:
+    janitor: keep
+`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	resources := []*Resource{
+		{Type: "AWS::EC2::KeyPair", Name: "prod-ci", Details: &ResourceDetails{}},
+		{Type: "AWS::EC2::KeyPair", Name: "dev", Details: &ResourceDetails{}},
+		{Type: "AWS::EC2::Volume", Name: "vol-1", Details: &ResourceDetails{Tags: map[string]string{"janitor": "keep"}}},
+		{Type: "AWS::EC2::Volume", Name: "vol-2", Details: &ResourceDetails{}},
+	}
+	candidates, protected := p.protect(resources)
+
+	wantProtectedBy := map[string]string{"dev": "keys", "vol-1": "tagged"}
+	for _, resource := range protected {
+		if want := wantProtectedBy[resource.Name]; resource.ProtectedBy != want {
+			t.Errorf("%s protected by %q, want %q", resource.Name, resource.ProtectedBy, want)
+		}
+	}
+	if len(protected) != len(wantProtectedBy) {
+		t.Errorf("%d resources protected, want %d", len(protected), len(wantProtectedBy))
+	}
+	for _, resource := range candidates {
+		if _, ok := wantProtectedBy[resource.Name]; ok {
+			t.Errorf("%s is a candidate, want protected", resource.Name)
+		}
+	}
+}
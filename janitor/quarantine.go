@@ -0,0 +1,171 @@
+package main
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"time"
+)
+
+// Tags of the quarantined resources: the time of the quarantine, and the id
+// of the report of the run that quarantined them.
+const quarantinedAtTag = "janitor:quarantined-at"
+const reportIDTag = "janitor:report-id"
+
+var errTagNotSupported = errors.New("tag not supported")
+
+// newReportID returns a unique id for the report of a run, ex:
+// 20190114T090425Z-1a2b3c4d
+func newReportID() string {
+	random := make([]byte, 4)
+	rand.Read(random)
+	return time.Now().UTC().Format("20060102T150405Z") + "-" + hex.EncodeToString(random)
+}
+
+// quarantinedAt returns the time a resource was quarantined at, from its
+// tags, and false if it was not quarantined.
+func quarantinedAt(resource *Resource) (time.Time, bool) {
+	if resource.Details == nil {
+		return time.Time{}, false
+	}
+	value, ok := resource.Details.Tags[quarantinedAtTag]
+	if !ok {
+		return time.Time{}, false
+	}
+	at, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		v("bad", quarantinedAtTag, "tag on", resource.Type, resource.Name, value)
+		return time.Time{}, false
+	}
+	return at, true
+}
+
+// quarantineParent returns the resource among resources, by resourceKey,
+// whose quarantine stands for a resource that cannot be tagged: its
+// Parent, ex: the private zone of the records of a cluster, or one of the
+// resources named by the QuarantineWith of its handler. It returns nil when
+// none of them is among resources.
+func quarantineParent(resource *Resource, resources map[string]*Resource) *Resource {
+	names := [][2]string{}
+	if resource.Parent != "" {
+		names = append(names, [2]string{resource.ParentType, resource.Parent})
+	}
+	if handler := resourceHandlers[resource.Type]; handler != nil && handler.QuarantineWith != nil {
+		with, err := handler.QuarantineWith(resource)
+		if err != nil {
+			logErr.Println("Got error finding what quarantines", resource.Type, resource.Name)
+			logErr.Println(err.Error())
+		}
+		names = append(names, with...)
+	}
+
+	for _, name := range names {
+		parent, ok := resources[resourceKey(name[0], resource.Region, name[1])]
+		if !ok {
+			continue
+		}
+		if handler := resourceHandlers[parent.Type]; handler != nil && handler.Tag != nil {
+			return parent
+		}
+	}
+	return nil
+}
+
+// resourcesByKey returns the resources by resourceKey.
+func resourcesByKey(resources []*Resource) map[string]*Resource {
+	result := map[string]*Resource{}
+	for _, resource := range resources {
+		result[resourceKey(resource.Type, resource.Region, resource.Name)] = resource
+	}
+	return result
+}
+
+// quarantineResources tags the resources with the quarantine time and the
+// report id, then makes them harmless: instances are stopped, the ingress
+// rules of security groups are revoked. Resources already quarantined keep
+// their quarantine time. Resources that cannot be tagged are quarantined with
+// their quarantine parent, see quarantineParent. It returns the resources that
+// could not be quarantined, with their Error set.
+func quarantineResources(resources []*Resource, reportID string) (failed []*Resource) {
+	failed = []*Resource{}
+	now := time.Now().UTC().Format(time.RFC3339)
+	untagged := []*Resource{}
+
+	for _, resource := range resources {
+		handler := resourceHandlers[resource.Type]
+		if handler == nil || handler.Tag == nil {
+			untagged = append(untagged, resource)
+			continue
+		}
+
+		var err error
+		if _, ok := quarantinedAt(resource); ok {
+			v("already quarantined", resource.Type, resource.Name)
+		} else {
+			logOut.Println("Quarantining", resource.Type, resource.Name)
+			err = handler.Tag(resource, map[string]string{
+				quarantinedAtTag: now,
+				reportIDTag:      reportID,
+			})
+		}
+		if err == nil && handler.Quarantine != nil {
+			err = handler.Quarantine(resource)
+		}
+		if err != nil {
+			logErr.Println("Could not quarantine", resource.Type, resource.Name)
+			logErr.Println(err.Error())
+			resource.Error = errorCode(err)
+			failed = append(failed, resource)
+		}
+	}
+
+	// The parents must have been quarantined
+	quarantined := resourcesByKey(resources)
+	for _, resource := range failed {
+		delete(quarantined, resourceKey(resource.Type, resource.Region, resource.Name))
+	}
+	for _, resource := range untagged {
+		if parent := quarantineParent(resource, quarantined); parent != nil {
+			v("quarantined with", parent.Type, parent.Name, resource.Type, resource.Name)
+			continue
+		}
+		logErr.Println("Type", resource.Type, "cannot be tagged,", resource.Name, "not quarantined")
+		resource.Error = errTagNotSupported.Error()
+		failed = append(failed, resource)
+	}
+
+	return failed
+}
+
+// filterQuarantined splits resources into the resources quarantined more
+// than grace ago, which can be reaped, the resources still in their grace
+// period, and the resources that were never quarantined, left alone. The
+// resources that cannot be tagged have the quarantine of their quarantine
+// parent.
+func filterQuarantined(resources []*Resource, grace time.Duration) (expired []*Resource, waiting []*Resource, notQuarantined []*Resource) {
+	expired = []*Resource{}
+	waiting = []*Resource{}
+	notQuarantined = []*Resource{}
+	byName := resourcesByKey(resources)
+
+	for _, resource := range resources {
+		tagged := resource
+		if handler := resourceHandlers[resource.Type]; handler == nil || handler.Tag == nil {
+			if parent := quarantineParent(resource, byName); parent != nil {
+				tagged = parent
+			}
+		}
+		at, ok := quarantinedAt(tagged)
+		switch {
+		case !ok:
+			notQuarantined = append(notQuarantined, resource)
+		case time.Since(at) < grace:
+			v("in grace period", resource.Type, resource.Name, at)
+			waiting = append(waiting, resource)
+		default:
+			expired = append(expired, resource)
+		}
+	}
+
+	return expired, waiting, notQuarantined
+}
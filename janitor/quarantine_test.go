@@ -0,0 +1,100 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+// quarantined returns the details of a resource quarantined at.
+func quarantined(at time.Time) *ResourceDetails {
+	return &ResourceDetails{Tags: map[string]string{
+		quarantinedAtTag: at.UTC().Format(time.RFC3339),
+		reportIDTag:      "20190114T090425Z-1a2b3c4d",
+	}}
+}
+
+func TestFilterQuarantined(t *testing.T) {
+	grace := 7 * 24 * time.Hour
+	old := time.Now().Add(-8 * 24 * time.Hour)
+	recent := time.Now().Add(-time.Hour)
+
+	tests := []struct {
+		name               string
+		resources          []*Resource
+		wantExpired        []string
+		wantWaiting        []string
+		wantNotQuarantined []string
+	}{
+		{
+			name: "by quarantine time",
+			resources: []*Resource{
+				{Type: "AWS::EC2::Instance", Name: "i-old", Details: quarantined(old)},
+				{Type: "AWS::EC2::Instance", Name: "i-recent", Details: quarantined(recent)},
+				{Type: "AWS::EC2::Instance", Name: "i-untagged", Details: &ResourceDetails{}},
+				{Type: "AWS::EC2::Instance", Name: "i-undescribed"},
+				{Type: "AWS::EC2::Instance", Name: "i-bad", Details: &ResourceDetails{Tags: map[string]string{quarantinedAtTag: "yesterday"}}},
+			},
+			wantExpired:        []string{"i-old"},
+			wantWaiting:        []string{"i-recent"},
+			wantNotQuarantined: []string{"i-untagged", "i-undescribed", "i-bad"},
+		},
+		{
+			name: "untaggable resources with their parent",
+			resources: []*Resource{
+				{Type: "AWS::Route53::HostedZone", Name: "Zold", Region: globalRegion, Details: quarantined(old)},
+				{Type: "AWS::Route53::HostedZone", Name: "Zrecent", Region: globalRegion, Details: quarantined(recent)},
+				{Type: "AWS::Route53::RecordSet", Name: "Zpublic:A:api.old.example.com.", Region: globalRegion, Parent: "Zold", ParentType: "AWS::Route53::HostedZone"},
+				{Type: "AWS::Route53::RecordSet", Name: "Zpublic:A:api.recent.example.com.", Region: globalRegion, Parent: "Zrecent", ParentType: "AWS::Route53::HostedZone"},
+				{Type: "AWS::Route53::RecordSet", Name: "Zpublic:A:api.gone.example.com.", Region: globalRegion, Parent: "Zgone", ParentType: "AWS::Route53::HostedZone"},
+				{Type: "AWS::Route53::RecordSet", Name: "Zpublic:A:api.example.com.", Region: globalRegion},
+			},
+			wantExpired:        []string{"Zold", "Zpublic:A:api.old.example.com."},
+			wantWaiting:        []string{"Zrecent", "Zpublic:A:api.recent.example.com."},
+			wantNotQuarantined: []string{"Zpublic:A:api.gone.example.com.", "Zpublic:A:api.example.com."},
+		},
+		{
+			name: "parents in the same region only",
+			resources: []*Resource{
+				{Type: "AWS::Route53::HostedZone", Name: "Zold", Region: globalRegion, Details: quarantined(old)},
+				{Type: "AWS::Route53::RecordSet", Name: "Zpublic:A:api.old.example.com.", Region: "us-east-1", Parent: "Zold", ParentType: "AWS::Route53::HostedZone"},
+			},
+			wantExpired:        []string{"Zold"},
+			wantWaiting:        []string{},
+			wantNotQuarantined: []string{"Zpublic:A:api.old.example.com."},
+		},
+		{
+			name: "same name, other type",
+			resources: []*Resource{
+				{Type: "AWS::IAM::Role", Name: "web", Region: globalRegion, Details: quarantined(old)},
+				{Type: "AWS::IAM::InstanceProfile", Name: "web", Region: globalRegion, Details: &ResourceDetails{}},
+			},
+			wantExpired:        []string{"web"},
+			wantWaiting:        []string{},
+			wantNotQuarantined: []string{"web"},
+		},
+	}
+
+	names := func(resources []*Resource) []string {
+		result := []string{}
+		for _, resource := range resources {
+			result = append(result, resource.Name)
+		}
+		return result
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			expired, waiting, notQuarantined := filterQuarantined(test.resources, grace)
+			if got := names(expired); !reflect.DeepEqual(got, test.wantExpired) {
+				t.Errorf("expired = %v, want %v", got, test.wantExpired)
+			}
+			if got := names(waiting); !reflect.DeepEqual(got, test.wantWaiting) {
+				t.Errorf("waiting = %v, want %v", got, test.wantWaiting)
+			}
+			if got := names(notQuarantined); !reflect.DeepEqual(got, test.wantNotQuarantined) {
+				t.Errorf("not quarantined = %v, want %v", got, test.wantNotQuarantined)
+			}
+		})
+	}
+}
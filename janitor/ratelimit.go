@@ -0,0 +1,140 @@
+package main
+
+import (
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"math/rand"
+	"sync"
+	"time"
+)
+
+// Requests per second allowed for each service, per region.
+// Services not listed use defaultServiceRate.
+var serviceRates = map[string]float64{
+	"cloudtrail":           2,
+	"ec2":                  20,
+	"elasticloadbalancing": 10,
+	"iam":                  10,
+	"s3":                   50,
+}
+
+var defaultServiceRate float64 = 10
+
+var maxBackoff = 60 * time.Second
+
+// rateLimiter is a token bucket shared by all the goroutines calling the
+// same service in the same region. When one of them gets throttled, all of
+// them pause for an exponentially growing delay.
+type rateLimiter struct {
+	mu          sync.Mutex
+	rate        float64
+	tokens      float64
+	last        time.Time
+	backoff     time.Duration
+	pausedUntil time.Time
+}
+
+var rateLimiters = map[string]*rateLimiter{}
+var rateLimitersMutex sync.Mutex
+
+func limiterFor(service string, region string) *rateLimiter {
+	rateLimitersMutex.Lock()
+	defer rateLimitersMutex.Unlock()
+
+	key := service + " " + region
+	if rateLimiters[key] == nil {
+		rate, ok := serviceRates[service]
+		if !ok {
+			rate = defaultServiceRate
+		}
+		rateLimiters[key] = &rateLimiter{
+			rate:   rate,
+			tokens: rate,
+			last:   time.Now(),
+		}
+	}
+	return rateLimiters[key]
+}
+
+// Wait blocks until a request can be sent.
+func (l *rateLimiter) Wait() {
+	for {
+		l.mu.Lock()
+		now := time.Now()
+
+		if now.Before(l.pausedUntil) {
+			delay := l.pausedUntil.Sub(now)
+			l.mu.Unlock()
+			time.Sleep(delay)
+			continue
+		}
+
+		// Refill, the bucket holds at most one second worth of requests
+		l.tokens += now.Sub(l.last).Seconds() * l.rate
+		if l.tokens > l.rate {
+			l.tokens = l.rate
+		}
+		l.last = now
+
+		if l.tokens >= 1 {
+			l.tokens--
+			l.mu.Unlock()
+			return
+		}
+
+		delay := time.Duration((1 - l.tokens) / l.rate * float64(time.Second))
+		l.mu.Unlock()
+		time.Sleep(delay)
+	}
+}
+
+// Throttled doubles the shared backoff and pauses every caller.
+func (l *rateLimiter) Throttled() {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.backoff == 0 {
+		l.backoff = time.Second
+	} else if l.backoff < maxBackoff {
+		l.backoff = l.backoff * 2
+		if l.backoff > maxBackoff {
+			l.backoff = maxBackoff
+		}
+	}
+	randomDelay := l.backoff + time.Duration(rand.Int63n(int64(l.backoff)))
+	if until := time.Now().Add(randomDelay); until.After(l.pausedUntil) {
+		l.pausedUntil = until
+		v("# Throttled, pausing all calls for", randomDelay)
+	}
+	l.tokens = 0
+}
+
+// Succeeded decreases the shared backoff.
+func (l *rateLimiter) Succeeded() {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.backoff = l.backoff / 2
+	if l.backoff < time.Second {
+		l.backoff = 0
+	}
+}
+
+// installRateLimiter makes every request sent by clients of the session, and
+// of its copies, go through the rate limiter of its service and region.
+func installRateLimiter(sess *session.Session) {
+	sess.Handlers.Send.PushFront(func(r *request.Request) {
+		limiterFor(r.ClientInfo.ServiceName, aws.StringValue(r.Config.Region)).Wait()
+	})
+	sess.Handlers.Retry.PushFront(func(r *request.Request) {
+		if request.IsErrorThrottle(r.Error) {
+			limiterFor(r.ClientInfo.ServiceName, aws.StringValue(r.Config.Region)).Throttled()
+		}
+	})
+	sess.Handlers.Complete.PushBack(func(r *request.Request) {
+		if r.Error == nil {
+			limiterFor(r.ClientInfo.ServiceName, aws.StringValue(r.Config.Region)).Succeeded()
+		}
+	})
+}
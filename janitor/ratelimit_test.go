@@ -0,0 +1,50 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestRateLimiterBackoff(t *testing.T) {
+	limiter := &rateLimiter{rate: 10, tokens: 10, last: time.Now()}
+
+	limiter.Throttled()
+	if limiter.backoff != time.Second || limiter.tokens != 0 {
+		t.Fatalf("after one throttle: backoff %v, %v tokens", limiter.backoff, limiter.tokens)
+	}
+	limiter.Throttled()
+	if limiter.backoff != 2*time.Second {
+		t.Errorf("backoff %v after two throttles, want 2s", limiter.backoff)
+	}
+	// The pause is random, between one and two backoffs
+	if pause := time.Until(limiter.pausedUntil); pause <= time.Second || pause > 4*time.Second {
+		t.Errorf("paused for %v", pause)
+	}
+
+	for i := 0; i < 10; i++ {
+		limiter.Throttled()
+	}
+	if limiter.backoff != maxBackoff {
+		t.Errorf("backoff %v, want it capped at %v", limiter.backoff, maxBackoff)
+	}
+
+	limiter.backoff = 2 * time.Second
+	limiter.Succeeded()
+	limiter.Succeeded()
+	if limiter.backoff != 0 {
+		t.Errorf("backoff %v after successes, want 0", limiter.backoff)
+	}
+}
+
+func TestRateLimiterWait(t *testing.T) {
+	limiter := &rateLimiter{rate: 200, tokens: 200, last: time.Now()}
+	start := time.Now()
+	// The first second worth of requests goes out at once, the next ones
+	// at the rate
+	for i := 0; i < 300; i++ {
+		limiter.Wait()
+	}
+	if elapsed := time.Since(start); elapsed < 400*time.Millisecond {
+		t.Errorf("300 requests at 200/s sent in %v", elapsed)
+	}
+}
@@ -0,0 +1,128 @@
+package main
+
+import (
+	"encoding/json"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/cloudtrail"
+	"github.com/aws/aws-sdk-go/service/iam"
+	"strings"
+	"time"
+)
+
+// Prefix of the principals standing for all the sessions of an IAM role,
+// ex: assumed-role/cloud-credential-operator
+const assumedRolePrefix = "assumed-role/"
+
+// spawnedPrincipal returns the principal a resource can act as: an instance,
+// through the session of its instance profile role, an IAM user, or the
+// sessions of an IAM role. It returns "" for other resources.
+func spawnedPrincipal(resource *Resource) string {
+	switch resource.Type {
+	case "AWS::EC2::Instance":
+		if strings.HasPrefix(resource.Name, "i-") {
+			return resource.Name
+		}
+	case "AWS::IAM::User":
+		return resource.Name
+	case "AWS::IAM::Role":
+		if !strings.HasPrefix(resource.Name, "arn:") {
+			return assumedRolePrefix + resource.Name
+		}
+	}
+	return ""
+}
+
+// roleSessions returns the names of the sessions of role started in region
+// after start, from its AssumeRole events.
+func roleSessions(region string, role string, start time.Time) []string {
+	result, err := iamClient().GetRole(&iam.GetRoleInput{
+		RoleName: aws.String(role),
+	})
+	if err != nil {
+		v("roleSessions(", role, ")", err.Error())
+		return []string{}
+	}
+
+	sessions := []string{}
+	seen := map[string]bool{}
+	input := &cloudtrail.LookupEventsInput{
+		StartTime: &start,
+		LookupAttributes: []*cloudtrail.LookupAttribute{
+			{
+				AttributeKey:   aws.String("ResourceName"),
+				AttributeValue: result.Role.Arn,
+			},
+		},
+	}
+	err = cloudtrailClient(region).LookupEventsPages(input,
+		func(page *cloudtrail.LookupEventsOutput, lastPage bool) bool {
+			for _, event := range page.Events {
+				if !strings.HasPrefix(aws.StringValue(event.EventName), "AssumeRole") {
+					continue
+				}
+				// The session is in the response of the event:
+				// assumedRoleUser.arn = arn:aws:sts::123:assumed-role/role/session
+				var record trailRecord
+				if err := json.Unmarshal([]byte(aws.StringValue(event.CloudTrailEvent)), &record); err != nil {
+					continue
+				}
+				response, _ := record.ResponseElements.(map[string]interface{})
+				user, _ := response["assumedRoleUser"].(map[string]interface{})
+				arn, _ := user["arn"].(string)
+				session := arn[strings.LastIndex(arn, "/")+1:]
+				if session != "" && !seen[session] {
+					seen[session] = true
+					sessions = append(sessions, session)
+				}
+			}
+			return true
+		})
+	if err != nil {
+		logErr.Println("Got error calling LookupEvents for role", role)
+		logErr.Println(err.Error())
+	}
+
+	v("roleSessions(", region, ",", role, ")", sessions)
+	return sessions
+}
+
+// searchRecursive adds the resources of the principals spawned by resources,
+// and of the principals spawned by those, after start, up to depth levels,
+// 0 for no limit. Each principal is searched once, starting with roots, so
+// cycles terminate.
+// The Chain of the resources found goes from the user to their principal.
+func searchRecursive(regions []string, resources []*Resource, depth int, roots []string, start time.Time) []*Resource {
+	visited := map[string]bool{}
+	for _, principal := range roots {
+		visited[principal] = true
+	}
+	newResources := resources
+
+	for level := 1; depth == 0 || level <= depth; level++ {
+		found := []*Resource{}
+		for _, resource := range newResources {
+			principal := spawnedPrincipal(resource)
+			if principal == "" || visited[principal] {
+				continue
+			}
+			visited[principal] = true
+			v("Level", level, "searching resources of", principal)
+
+			chain := append(append([]string{}, resource.Chain...), principal)
+			spawned := searchCloudtrail(regions, principal, start)
+			for _, spawnedResource := range spawned {
+				spawnedResource.Chain = chain
+			}
+			found = mergeResources(found, spawned)
+		}
+
+		before := len(resources)
+		resources = mergeResources(resources, found)
+		newResources = resources[before:]
+		if len(newResources) == 0 {
+			break
+		}
+	}
+
+	return resources
+}
@@ -0,0 +1,77 @@
+package main
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSpawnedPrincipal(t *testing.T) {
+	tests := []struct {
+		resource Resource
+		want     string
+	}{
+		{Resource{Type: "AWS::EC2::Instance", Name: "i-0123"}, "i-0123"},
+		{Resource{Type: "AWS::IAM::User", Name: "bot"}, "bot"},
+		{Resource{Type: "AWS::IAM::Role", Name: "worker"}, "assumed-role/worker"},
+		// Roles found by ARN in CloudTrail are not searched
+		{Resource{Type: "AWS::IAM::Role", Name: "arn:aws:iam::123456789012:role/worker"}, ""},
+		{Resource{Type: "AWS::EC2::Volume", Name: "vol-0123"}, ""},
+	}
+	for _, test := range tests {
+		if got := spawnedPrincipal(&test.resource); got != test.want {
+			t.Errorf("%s %s spawns %q, want %q", test.resource.Type, test.resource.Name, got, test.want)
+		}
+	}
+}
+
+// alice starts i-web, whose role creates the worker role, whose sessions
+// start i-batch, which creates the worker role again and a bucket.
+func TestSearchRecursive(t *testing.T) {
+	defer func(location string, archive *trailArchive) { trailLocation, trail = location, archive }(trailLocation, trail)
+	trailLocation = "testdata"
+
+	start := time.Date(2019, 1, 14, 7, 0, 0, 0, time.UTC)
+	events := []*trailEvent{
+		{EventName: "RunInstances", UserName: "alice", Arn: "arn:aws:iam::123456789012:user/alice", Ids: [][2]string{{"AWS::EC2::Instance", "i-web"}}},
+		{EventName: "CreateRole", Arn: "arn:aws:sts::123456789012:assumed-role/web/i-web", Ids: [][2]string{{"AWS::IAM::Role", "worker"}}},
+		{EventName: "RunInstances", Arn: "arn:aws:sts::123456789012:assumed-role/worker/job-1", Ids: [][2]string{{"AWS::EC2::Instance", "i-batch"}}},
+		{EventName: "CreateRole", Arn: "arn:aws:sts::123456789012:assumed-role/batch/i-batch", Ids: [][2]string{{"AWS::IAM::Role", "worker"}}},
+		{EventName: "CreateBucket", Arn: "arn:aws:sts::123456789012:assumed-role/batch/i-batch", Ids: [][2]string{{"AWS::S3::Bucket", "results"}}},
+	}
+	trail = &trailArchive{events: events, byPrincipal: map[string][]*trailEvent{}}
+	for i, event := range events {
+		event.AwsRegion = "us-east-1"
+		event.EventTime = start.Add(time.Duration(i) * time.Minute)
+		for _, principal := range eventPrincipals(event) {
+			trail.byPrincipal[principal] = append(trail.byPrincipal[principal], event)
+		}
+	}
+
+	search := func(depth int) map[string]string {
+		found := searchCloudtrail([]string{"us-east-1"}, "alice", start)
+		chains := map[string]string{}
+		for _, resource := range searchRecursive([]string{"us-east-1"}, found, depth, []string{"alice"}, start) {
+			chains[resource.Name] = strings.Join(resource.Chain, " > ")
+		}
+		return chains
+	}
+
+	if got, want := search(1), map[string]string{
+		"i-web":  "alice",
+		"worker": "alice > i-web",
+	}; !reflect.DeepEqual(got, want) {
+		t.Errorf("depth 1: %v, want %v", got, want)
+	}
+
+	// The worker role found again by i-batch is not searched twice
+	if got, want := search(0), map[string]string{
+		"i-web":   "alice",
+		"worker":  "alice > i-web",
+		"i-batch": "alice > i-web > assumed-role/worker",
+		"results": "alice > i-web > assumed-role/worker > i-batch",
+	}; !reflect.DeepEqual(got, want) {
+		t.Errorf("no depth limit: %v, want %v", got, want)
+	}
+}
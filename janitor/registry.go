@@ -0,0 +1,106 @@
+package main
+
+import (
+	"time"
+)
+
+// ResourceDetails is what a handler knows about a resource beyond its id.
+type ResourceDetails struct {
+	Owner        string            `json:"owner,omitempty" yaml:"owner,omitempty"`
+	Tags         map[string]string `json:"tags,omitempty" yaml:"tags,omitempty"`
+	CreationTime *time.Time        `json:"creation_time,omitempty" yaml:"creation_time,omitempty"`
+	// Type, state and size of instances and volumes, to estimate their cost
+	InstanceType string `json:"instance_type,omitempty" yaml:"instance_type,omitempty"`
+	State        string `json:"state,omitempty" yaml:"state,omitempty"`
+	VolumeType   string `json:"volume_type,omitempty" yaml:"volume_type,omitempty"`
+	VolumeSize   int64  `json:"volume_size,omitempty" yaml:"volume_size,omitempty"`
+}
+
+// resourceHandler implements the operations for one CloudTrail resource
+// type, ex: AWS::EC2::Instance. Each type registers its handler from the
+// init() function of its own file.
+type resourceHandler struct {
+	// CreatedBy lists the events creating a resource of this type, ex:
+	// RunInstances. A resource without creation time is only considered
+	// created by the user when its oldest event is one of them.
+	CreatedBy []string
+
+	// ArnTypes lists the service:type of the ARNs of this type, ex:
+	// ec2:instance, to find its resources by tags.
+	ArnTypes []string
+
+	// ByArn is true when CloudTrail names the resources of this type by
+	// their ARN, otherwise by the last part of it, ex: the instance id.
+	ByArn bool
+
+	// TrailIdKeys lists the keys of the ids of this type in the request
+	// parameters and response elements of CloudTrail records, ex:
+	// instanceId.
+	TrailIdKeys []string
+
+	// NukeType is the aws-nuke resource type, "" when aws-nuke deletes the
+	// resources of this type along with another one, ex: listeners with
+	// their load balancer.
+	NukeType string
+
+	// Canonical returns the name the handler knows a resource of the region
+	// by, ex: the name of a role ARN, so that a resource found by its name
+	// and by its ARN is checked and reported once. Optional.
+	Canonical func(region string, name string) string
+
+	// Exists returns true if the resource still exists, false if it does
+	// not. It returns an error when existence could not be verified.
+	Exists func(resource *Resource) (bool, error)
+
+	// ExistsBatch returns the set of names that still exist among names,
+	// all in the same region, and the errors of the names whose existence
+	// could not be verified. Optional, it saves API calls when a type has
+	// many resources.
+	ExistsBatch func(region string, names []string) (map[string]bool, map[string]error)
+
+	// BatchSize is the max number of names passed to ExistsBatch.
+	BatchSize int
+
+	// Describe returns the owner, tags and creation time of the resource,
+	// and what its cost depends on. Optional.
+	Describe func(resource *Resource) (*ResourceDetails, error)
+
+	// Keep returns why the resource must be left alone although the user
+	// created it, ex: a hosted zone other principals added records to, ""
+	// to let it be deleted. Optional.
+	Keep func(resource *Resource) (string, error)
+
+	// Delete deletes the resource. Optional.
+	Delete func(resource *Resource) error
+
+	// DeleteScript returns the shell commands doing what Delete does, for
+	// the -emit=script deletion script. aws is the aws CLI command with the
+	// region and profile options. Optional.
+	DeleteScript func(resource *Resource, aws string) []string
+
+	// Tag adds tags to the resource, for -quarantine. Optional, resources
+	// that cannot be tagged are neither quarantined nor reaped.
+	Tag func(resource *Resource, tags map[string]string) error
+
+	// QuarantineWith returns the types and names of the resources in the
+	// same region whose quarantine tags stand for this one, for the types
+	// that cannot be tagged, ex: the route table of an association. Optional.
+	QuarantineWith func(resource *Resource) ([][2]string, error)
+
+	// Quarantine makes the resource harmless without deleting it, ex: stops
+	// an instance. Optional.
+	Quarantine func(resource *Resource) error
+
+	// DeleteAfter lists the resource types that must be deleted before
+	// this one.
+	DeleteAfter []string
+}
+
+var resourceHandlers = map[string]*resourceHandler{}
+
+func registerResourceType(resourceType string, handler *resourceHandler) {
+	if _, ok := resourceHandlers[resourceType]; ok {
+		panic("resource type " + resourceType + " registered twice")
+	}
+	resourceHandlers[resourceType] = handler
+}
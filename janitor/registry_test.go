@@ -0,0 +1,36 @@
+package main
+
+import (
+	"testing"
+)
+
+func TestRegisteredHandlers(t *testing.T) {
+	if len(resourceHandlers) == 0 {
+		t.Fatal("no resource type registered")
+	}
+	for resourceType, handler := range resourceHandlers {
+		if handler.Exists == nil {
+			t.Errorf("%s cannot check existence", resourceType)
+		}
+		// Deletion order only knows registered types
+		for _, before := range handler.DeleteAfter {
+			if _, ok := resourceHandlers[before]; !ok {
+				t.Errorf("%s is deleted after %s, which is not registered", resourceType, before)
+			}
+		}
+	}
+}
+
+func TestRegisterTwice(t *testing.T) {
+	resourceType := "AWS::EC2::Instance"
+	handler := resourceHandlers[resourceType]
+	defer func() {
+		if recover() == nil {
+			t.Error("registering a type twice does not panic")
+		}
+		if resourceHandlers[resourceType] != handler {
+			t.Error("handler replaced")
+		}
+	}()
+	registerResourceType(resourceType, &resourceHandler{})
+}
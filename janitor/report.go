@@ -0,0 +1,507 @@
+package main
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"gopkg.in/yaml.v2"
+	"io"
+	"log"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Report is the result of a janitor run.
+type Report struct {
+	// ID is the id of the run, quarantined resources are tagged with it
+	ID          string                 `json:"id" yaml:"id"`
+	Account     string                 `json:"account,omitempty" yaml:"account,omitempty"`
+	User        string                 `json:"user" yaml:"user"`
+	Lookups     []string               `json:"lookups,omitempty" yaml:"lookups,omitempty"`
+	Users       []*userStart           `json:"users,omitempty" yaml:"users,omitempty"`
+	ByUser      map[string][]*Resource `json:"by_user,omitempty" yaml:"by_user,omitempty"`
+	Tags        []string               `json:"tags,omitempty" yaml:"tags,omitempty"`
+	StartTime   time.Time              `json:"start_time" yaml:"start_time"`
+	Regions     []string               `json:"regions" yaml:"regions"`
+	Resources   []*Resource            `json:"resources" yaml:"resources"`
+	Unverified  []*Resource            `json:"unverified,omitempty" yaml:"unverified,omitempty"`
+	Protected   []*Resource            `json:"protected,omitempty" yaml:"protected,omitempty"`
+	Preexisting []*Resource            `json:"preexisting,omitempty" yaml:"preexisting,omitempty"`
+	Unsupported map[string]int         `json:"unsupported,omitempty" yaml:"unsupported,omitempty"`
+	// Cost is the estimated cost of Resources
+	Cost *Cost `json:"cost,omitempty" yaml:"cost,omitempty"`
+	// BelowMinCost is the number of resources collapsed by -min-cost, they are
+	// still in Resources
+	BelowMinCost int `json:"below_min_cost,omitempty" yaml:"below_min_cost,omitempty"`
+	// Quarantined is the number of resources quarantined, with -quarantine
+	Quarantined int `json:"quarantined,omitempty" yaml:"quarantined,omitempty"`
+	// NotQuarantined are the resources that could not be quarantined, with
+	// -quarantine, or that were never quarantined, with -reap
+	NotQuarantined []*Resource `json:"not_quarantined,omitempty" yaml:"not_quarantined,omitempty"`
+	// InGracePeriod are the quarantined resources not reaped yet, with -reap
+	InGracePeriod []*Resource `json:"in_grace_period,omitempty" yaml:"in_grace_period,omitempty"`
+	// FilteredEvents is the number of events excluded by each event rule
+	FilteredEvents map[string]int    `json:"filtered_events,omitempty" yaml:"filtered_events,omitempty"`
+	Deleted        int               `json:"deleted,omitempty" yaml:"deleted,omitempty"`
+	NotDeleted     []*Resource       `json:"not_deleted,omitempty" yaml:"not_deleted,omitempty"`
+	Provenance     []*ProvenanceNode `json:"provenance,omitempty" yaml:"provenance,omitempty"`
+	// Accounts are the reports of each account, with several accounts
+	Accounts []*Report `json:"accounts,omitempty" yaml:"accounts,omitempty"`
+	// Error is why the account could not be audited, ex: its CloudTrail
+	// logs could not be read
+	Error string `json:"error,omitempty" yaml:"error,omitempty"`
+	// RoleArn is the role the account was audited through
+	RoleArn string `json:"role_arn,omitempty" yaml:"role_arn,omitempty"`
+}
+
+// ProvenanceNode is a principal or a resource, with the resources it
+// introduced. A resource that is also a principal, ex: an instance, has both.
+type ProvenanceNode struct {
+	Principal string            `json:"principal,omitempty" yaml:"principal,omitempty"`
+	Resource  *Resource         `json:"resource,omitempty" yaml:"resource,omitempty"`
+	Children  []*ProvenanceNode `json:"children,omitempty" yaml:"children,omitempty"`
+}
+
+// provenanceTree returns the tree of the principals, from the user, and of
+// the resources they introduced, built from the Chain of the resources.
+func provenanceTree(resources []*Resource) []*ProvenanceNode {
+	roots := []*ProvenanceNode{}
+	nodes := map[string]*ProvenanceNode{}
+	resourceNodes := map[string]*ProvenanceNode{}
+	children := []*Resource{}
+
+	var principalNode func(chain []string) *ProvenanceNode
+	principalNode = func(chain []string) *ProvenanceNode {
+		key := strings.Join(chain, " ")
+		if node, ok := nodes[key]; ok {
+			return node
+		}
+		node := &ProvenanceNode{Principal: chain[len(chain)-1]}
+		nodes[key] = node
+		if len(chain) == 1 {
+			roots = append(roots, node)
+		} else {
+			parent := principalNode(chain[:len(chain)-1])
+			parent.Children = append(parent.Children, node)
+		}
+		return node
+	}
+
+	addResource := func(resource *Resource) {
+		chain := resource.Chain
+		if len(chain) == 0 {
+			chain = []string{resource.Principal}
+		}
+		if principal := spawnedPrincipal(resource); principal != "" {
+			node := principalNode(append(append([]string{}, chain...), principal))
+			node.Resource = resource
+			resourceNodes[resourceKey(resource.Type, resource.Region, resource.Name)] = node
+			return
+		}
+		node := &ProvenanceNode{Resource: resource}
+		resourceNodes[resourceKey(resource.Type, resource.Region, resource.Name)] = node
+		parent := principalNode(chain)
+		parent.Children = append(parent.Children, node)
+	}
+
+	for _, resource := range resources {
+		if resource.Parent != "" {
+			children = append(children, resource)
+			continue
+		}
+		addResource(resource)
+	}
+	// Children go under their parent resource, ex: the snapshots of an AMI
+	for _, resource := range children {
+		if parent, ok := resourceNodes[resourceKey(resource.ParentType, resource.Region, resource.Parent)]; ok {
+			parent.Children = append(parent.Children, &ProvenanceNode{Resource: resource})
+			continue
+		}
+		addResource(resource)
+	}
+
+	return roots
+}
+
+// describeResources fills the details of the resources whose handler knows
+// how to describe them, unless already described.
+func describeResources(resources []*Resource) {
+	for _, resource := range resources {
+		handler, ok := resourceHandlers[resource.Type]
+		if !ok || handler.Describe == nil || resource.Details != nil {
+			continue
+		}
+
+		details, err := handler.Describe(resource)
+		if err != nil {
+			logErr.Println("Got error describing", resource.Type, resource.Name)
+			logErr.Println(err.Error())
+			continue
+		}
+		resource.Details = details
+	}
+}
+
+// writeReport writes the report as a json, yaml or csv document.
+func writeReport(w io.Writer, report *Report, format string) error {
+	switch format {
+	case "json":
+		encoder := json.NewEncoder(w)
+		encoder.SetIndent("", " ")
+		return encoder.Encode(report)
+
+	case "yaml":
+		out, err := yaml.Marshal(report)
+		if err != nil {
+			return err
+		}
+		_, err = w.Write(out)
+		return err
+
+	case "csv":
+		writer := csv.NewWriter(w)
+		writer.Write([]string{
+			"account",
+			"user",
+			"status",
+			"region",
+			"type",
+			"name",
+			"event_name",
+			"event_time",
+			"attributed_to",
+			"error",
+			"protected_by",
+			"found_by",
+			"event_id",
+			"chain",
+			"monthly_cost",
+			"parent",
+		})
+		for _, accountReport := range append([]*Report{report}, report.Accounts...) {
+			writeCSVRows(writer, accountReport)
+		}
+		writer.Flush()
+		return writer.Error()
+	}
+
+	return nil
+}
+
+// resourcesByUser groups resources by the -users they were found for.
+func resourcesByUser(resources []*Resource) map[string][]*Resource {
+	result := map[string][]*Resource{}
+	for _, resource := range resources {
+		for _, user := range resource.Users {
+			result[user] = append(result[user], resource)
+		}
+	}
+	return result
+}
+
+// printActivity prints the users and start times searched.
+func printActivity(logger *log.Logger, report *Report) {
+	if len(report.Users) == 0 {
+		logger.Println("Activity of user", report.User, "starting at ", report.StartTime)
+		return
+	}
+	for _, user := range report.Users {
+		logger.Println("Activity of user", user.User, "starting at ", user.StartTime)
+	}
+}
+
+// writeCSVRows writes a row per resource of the report.
+func writeCSVRows(writer *csv.Writer, report *Report) {
+	for _, status := range []string{"existing", "unverified", "protected", "preexisting", "in_grace_period", "not_quarantined"} {
+		resources := report.Resources
+		switch status {
+		case "in_grace_period":
+			resources = report.InGracePeriod
+		case "not_quarantined":
+			// With -quarantine, they are existing resources, with an error
+			if quarantineMode {
+				continue
+			}
+			resources = report.NotQuarantined
+		case "unverified":
+			resources = report.Unverified
+		case "protected":
+			resources = report.Protected
+		case "preexisting":
+			resources = report.Preexisting
+		}
+		for _, resource := range resources {
+			user := report.User
+			if len(resource.Users) > 0 {
+				user = strings.Join(resource.Users, " ")
+			}
+			cost := ""
+			if resource.Cost != nil {
+				cost = fmt.Sprintf("%.2f", resource.Cost.Monthly)
+			}
+			writer.Write([]string{
+				report.Account,
+				user,
+				status,
+				resource.Region,
+				resource.Type,
+				resource.Name,
+				resource.EventName,
+				resource.EventTime.Format(time.RFC3339),
+				resource.Principal,
+				resource.Error,
+				resource.ProtectedBy,
+				strings.Join(resource.FoundBy, " "),
+				resource.EventID,
+				strings.Join(resource.Chain, " > "),
+				cost,
+				resource.Parent,
+			})
+		}
+	}
+}
+
+func printTextReport(report *Report) {
+	if len(report.Resources) == 0 && len(report.Unverified) == 0 &&
+		len(report.Protected) == 0 && len(report.Preexisting) == 0 &&
+		len(report.InGracePeriod) == 0 && len(report.NotQuarantined) == 0 {
+		printActivity(logOut, report)
+		if report.Account != "" {
+			logOut.Println("Account:", report.Account)
+		}
+		if report.Error != "" {
+			logErr.Println("Account not audited:", report.Error)
+			return
+		}
+		logOut.Println("No resources found.")
+		printCost(report)
+		printUnsupported(report.Unsupported)
+		printFilteredEvents(logOut, report.FilteredEvents)
+		return
+	}
+
+	printActivity(logReport, report)
+	logReport.Println("Report id:", report.ID)
+	if report.Account != "" {
+		logReport.Println("Account:", report.Account)
+	}
+	if len(report.Lookups) > 0 {
+		logReport.Println("Lookups:", strings.Join(report.Lookups, ", "))
+	}
+	if len(report.Tags) > 0 {
+		logReport.Println("Tags:", strings.Join(report.Tags, ", "))
+	}
+	if len(report.Regions) > 1 {
+		logReport.Println("Regions:", strings.Join(report.Regions, ", "))
+	}
+	logReport.Println("Number of resources still existing:", len(report.Resources))
+	listed := []*Resource{}
+	cheap := []*Resource{}
+	for _, resource := range report.Resources {
+		if resource.BelowMinCost {
+			cheap = append(cheap, resource)
+		} else {
+			listed = append(listed, resource)
+		}
+	}
+	printResources(report.Regions, listed)
+	printCost(report)
+
+	if len(cheap) > 0 {
+		// Collapsed, one line each, they are deleted too
+		logReport.Println()
+		logReport.Printf("Number of resources cheaper than $%.2f/month, still deleted: %d", minCost, len(cheap))
+		for _, resource := range cheap {
+			logReport.Println(" ", resource.Region, resource.Type, resource.Name)
+		}
+	}
+
+	if len(report.Unverified) > 0 {
+		logReport.Println()
+		logReport.Println("Number of resources that could not be verified:", len(report.Unverified))
+		printResources(report.Regions, report.Unverified)
+	}
+
+	if len(report.Protected) > 0 {
+		logReport.Println()
+		logReport.Println("Number of resources protected:", len(report.Protected))
+		printResources(report.Regions, report.Protected)
+	}
+
+	if len(report.Preexisting) > 0 {
+		logReport.Println()
+		logReport.Println("Number of resources created before the start time, only modified:", len(report.Preexisting))
+		printResources(report.Regions, report.Preexisting)
+	}
+
+	if quarantineMode {
+		logReport.Println()
+		logReport.Println("Number of resources quarantined:", report.Quarantined)
+		if len(report.NotQuarantined) > 0 {
+			logReport.Println("Number of resources that could not be quarantined:", len(report.NotQuarantined))
+			printResources(report.Regions, report.NotQuarantined)
+		}
+	}
+
+	if len(report.InGracePeriod) > 0 {
+		logReport.Println()
+		logReport.Println("Number of resources quarantined less than", gracePeriod, "ago, not reaped yet:", len(report.InGracePeriod))
+		printResources(report.Regions, report.InGracePeriod)
+	}
+
+	if reapMode && len(report.NotQuarantined) > 0 {
+		logReport.Println()
+		logReport.Println("Number of resources never quarantined, left alone:", len(report.NotQuarantined))
+		printResources(report.Regions, report.NotQuarantined)
+	}
+
+	for _, user := range report.Users {
+		if resources := report.ByUser[user.User]; len(resources) > 0 {
+			logReport.Println()
+			logReport.Println("Number of resources of user", user.User+":", len(resources))
+			for _, resource := range resources {
+				logReport.Println(resource.Type, resource.Name, "("+resource.Region+")")
+			}
+		}
+	}
+
+	if len(report.Provenance) > 0 {
+		logReport.Println()
+		logReport.Println("Provenance:")
+		printProvenance(report.Provenance, "")
+	}
+	printUnsupported(report.Unsupported)
+	printFilteredEvents(logOut, report.FilteredEvents)
+}
+
+func printTextDeleteReport(report *Report) {
+	logReport.Println()
+	logReport.Println("Number of resources deleted:", report.Deleted)
+	if len(report.NotDeleted) > 0 {
+		logReport.Println("Number of resources that could not be deleted:", len(report.NotDeleted))
+		printResources(report.Regions, report.NotDeleted)
+	}
+}
+
+// printResources prints resources grouped by region, global resources last.
+// The region headers are omitted when a single region was searched.
+func printResources(regions []string, resources []*Resource) {
+	groups := map[string][]*Resource{}
+	for _, resource := range resources {
+		groups[resource.Region] = append(groups[resource.Region], resource)
+	}
+
+	for _, region := range append(append([]string{}, regions...), globalRegion) {
+		if len(groups[region]) == 0 {
+			continue
+		}
+		logReport.Println()
+		if len(regions) > 1 {
+			logReport.Println("[" + region + "]")
+		}
+		for _, resource := range groups[region] {
+			if resource.Error != "" {
+				logReport.Println(resource.Type, resource.Name, "("+resource.Error+")")
+				continue
+			}
+			if resource.ProtectedBy != "" {
+				logReport.Println(resource.Type, resource.Name, "(protected by "+resource.ProtectedBy+")")
+				continue
+			}
+			line := []interface{}{resource.Type, resource.Name}
+			if backend != backendCloudtrail || !(len(resource.FoundBy) == 1 && resource.FoundBy[0] == backendCloudtrail) {
+				line = append(line, "(found by "+strings.Join(resource.FoundBy, ", ")+")")
+			}
+			if resource.Parent != "" {
+				line = append(line, "(of "+resource.Parent+")")
+			}
+			if resource.Cost != nil {
+				line = append(line, fmt.Sprintf("($%.2f/month)", resource.Cost.Monthly))
+			}
+			logReport.Println(line...)
+			if resource.Details != nil {
+				printDetails(resource.Details)
+			}
+		}
+	}
+}
+
+// printCost prints the estimated cost of the resources still existing.
+func printCost(report *Report) {
+	if report.Cost != nil && report.Cost.Monthly > 0 {
+		logReport.Println()
+		logReport.Printf("Estimated cost of the resources still existing: $%.2f/hour, $%.2f/month", report.Cost.Hourly, report.Cost.Monthly)
+	}
+}
+
+// printProvenance prints the provenance tree, indented by depth.
+func printProvenance(nodes []*ProvenanceNode, indent string) {
+	for _, node := range nodes {
+		if resource := node.Resource; resource != nil {
+			event := resource.Region + ", " + resource.EventName + " " + resource.EventTime.Format(time.RFC3339)
+			if resource.EventID != "" {
+				event += " " + resource.EventID
+			}
+			if resource.EventName == "" {
+				event = resource.Region + ", found by " + strings.Join(resource.FoundBy, ", ")
+			}
+			logReport.Println(indent+resource.Type, resource.Name, "("+event+")")
+		} else {
+			logReport.Println(indent + node.Principal)
+		}
+		printProvenance(node.Children, indent+"    ")
+	}
+}
+
+// printDetails prints the owner, creation time and tags of a resource.
+func printDetails(details *ResourceDetails) {
+	if details.Owner != "" {
+		logReport.Println("    owner:", details.Owner)
+	}
+	if details.CreationTime != nil {
+		logReport.Println("    created:", details.CreationTime.Format(time.RFC3339))
+	}
+	if details.InstanceType != "" {
+		logReport.Println("    instance type:", details.InstanceType, details.State)
+	}
+	if details.VolumeType != "" || details.VolumeSize > 0 {
+		logReport.Println("    volume:", details.VolumeType, details.VolumeSize, "GiB")
+	}
+	keys := []string{}
+	for key := range details.Tags {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		logReport.Println("    tag:", key+"="+details.Tags[key])
+	}
+}
+
+// printFilteredEvents prints the number of events excluded by each event
+// rule.
+func printFilteredEvents(logger *log.Logger, counts map[string]int) {
+	rules := []string{}
+	for rule := range counts {
+		rules = append(rules, rule)
+	}
+	sort.Strings(rules)
+
+	for _, rule := range rules {
+		logger.Println("Events excluded by rule", rule+":", counts[rule])
+	}
+}
+
+// printUnsupported summarizes the resources that could not be checked
+// because their type has no handler.
+func printUnsupported(unsupported map[string]int) {
+	types := []string{}
+	for resourceType := range unsupported {
+		types = append(types, resourceType)
+	}
+	sort.Strings(types)
+
+	for _, resourceType := range types {
+		logErr.Println("Type", resourceType, "not supported,", unsupported[resourceType], "resources not checked")
+	}
+}
@@ -0,0 +1,202 @@
+package main
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"gopkg.in/yaml.v2"
+	"log"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestWriteReport(t *testing.T) {
+	start := time.Date(2019, 1, 14, 7, 4, 25, 0, time.UTC)
+	report := &Report{
+		User:      "alice",
+		StartTime: start,
+		Regions:   []string{"us-east-1"},
+		Resources: []*Resource{
+			{
+				Type:      "AWS::EC2::Instance",
+				Name:      "i-0123",
+				Region:    "us-east-1",
+				EventName: "RunInstances",
+				EventTime: start.Add(time.Minute),
+				Principal: "alice",
+			},
+			{Type: "AWS::S3::Bucket", Name: "alice-logs", Region: globalRegion, EventName: "CreateBucket", EventTime: start},
+		},
+	}
+
+	decoders := map[string]func([]byte, interface{}) error{
+		"json": json.Unmarshal,
+		"yaml": yaml.Unmarshal,
+	}
+	for format, decode := range decoders {
+		var out bytes.Buffer
+		if err := writeReport(&out, report, format); err != nil {
+			t.Fatalf("%s: %v", format, err)
+		}
+		decoded := &Report{}
+		if err := decode(out.Bytes(), decoded); err != nil {
+			t.Fatalf("%s: %v\n%s", format, err, out.String())
+		}
+		if decoded.User != "alice" || !decoded.StartTime.Equal(start) || len(decoded.Resources) != 2 ||
+			decoded.Resources[0].Name != "i-0123" || !decoded.Resources[0].EventTime.Equal(start.Add(time.Minute)) {
+			t.Errorf("%s: decoded %+v", format, decoded)
+		}
+	}
+
+	var out bytes.Buffer
+	if err := writeReport(&out, report, "csv"); err != nil {
+		t.Fatal(err)
+	}
+	rows, err := csv.NewReader(&out).ReadAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(rows) != 3 {
+		t.Fatalf("%d rows, want the header and a row per resource", len(rows))
+	}
+	column := map[string]int{}
+	for i, name := range rows[0] {
+		column[name] = i
+	}
+	for i, want := range [][2]string{{"i-0123", "us-east-1"}, {"alice-logs", "global"}} {
+		row := rows[i+1]
+		if row[column["name"]] != want[0] || row[column["region"]] != want[1] {
+			t.Errorf("row %d: %v, want %v", i+1, row, want)
+		}
+	}
+	if got := rows[1][column["event_time"]]; got != "2019-01-14T07:05:25Z" {
+		t.Errorf("event time %q", got)
+	}
+}
+
+// The resources whose existence could not be verified keep their error in
+// every format, and are rows of their own status in csv.
+func TestWriteReportUnverified(t *testing.T) {
+	report := &Report{
+		User:       "alice",
+		Regions:    []string{"us-east-1"},
+		Resources:  []*Resource{{Type: "AWS::EC2::Instance", Name: "i-0123", Region: "us-east-1"}},
+		Unverified: []*Resource{{Type: "AWS::EC2::Volume", Name: "vol-0123", Region: "us-east-1", Error: "RequestLimitExceeded"}},
+	}
+
+	var out bytes.Buffer
+	if err := writeReport(&out, report, "json"); err != nil {
+		t.Fatal(err)
+	}
+	decoded := &Report{}
+	if err := json.Unmarshal(out.Bytes(), decoded); err != nil {
+		t.Fatal(err)
+	}
+	if len(decoded.Unverified) != 1 || decoded.Unverified[0].Error != "RequestLimitExceeded" {
+		t.Errorf("unverified %+v", decoded.Unverified)
+	}
+
+	out.Reset()
+	if err := writeReport(&out, report, "csv"); err != nil {
+		t.Fatal(err)
+	}
+	rows, err := csv.NewReader(&out).ReadAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+	column := map[string]int{}
+	for i, name := range rows[0] {
+		column[name] = i
+	}
+	for i, want := range [][3]string{{"existing", "i-0123", ""}, {"unverified", "vol-0123", "RequestLimitExceeded"}} {
+		row := rows[i+1]
+		if row[column["status"]] != want[0] || row[column["name"]] != want[1] || row[column["error"]] != want[2] {
+			t.Errorf("row %d: %v, want %v", i+1, row, want)
+		}
+	}
+}
+
+// Resources cheaper than -min-cost are deleted, they must be in every listing.
+func TestMinCostListing(t *testing.T) {
+	defer func(logger *log.Logger, min float64) {
+		logReport, minCost = logger, min
+	}(logReport, minCost)
+	minCost = 10
+
+	report := &Report{
+		User:    "alice",
+		Regions: []string{"us-east-1"},
+		Resources: []*Resource{
+			{Type: "AWS::EC2::Instance", Name: "i-0123", Region: "us-east-1", Cost: &Cost{Hourly: 0.192, Monthly: 140.16}},
+			{Type: "AWS::EC2::Subnet", Name: "subnet-0123", Region: "us-east-1", BelowMinCost: true},
+			{Type: "AWS::EC2::Volume", Name: "vol-0123", Region: "us-east-1", Cost: &Cost{Monthly: 0.80}, BelowMinCost: true},
+		},
+		Cost:         &Cost{Hourly: 0.192, Monthly: 140.96},
+		BelowMinCost: 2,
+	}
+
+	var out bytes.Buffer
+	if err := writeReport(&out, report, "csv"); err != nil {
+		t.Fatal(err)
+	}
+	rows, err := csv.NewReader(&out).ReadAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(rows) != 4 {
+		t.Errorf("csv has %d rows, want the header and the 3 resources", len(rows))
+	}
+
+	var text bytes.Buffer
+	logReport = log.New(&text, "", 0)
+	printTextReport(report)
+	listing := text.String()
+	for _, want := range []string{
+		"AWS::EC2::Instance i-0123",
+		"Number of resources cheaper than $10.00/month, still deleted: 2",
+		"  us-east-1 AWS::EC2::Subnet subnet-0123\n",
+		"  us-east-1 AWS::EC2::Volume vol-0123\n",
+	} {
+		if !strings.Contains(listing, want) {
+			t.Errorf("text report lacks %q:\n%s", want, listing)
+		}
+	}
+}
+
+func TestProvenanceTree(t *testing.T) {
+	resources := []*Resource{
+		{Type: "AWS::EC2::Instance", Name: "i-web", Region: "us-east-1", Chain: []string{"alice"}},
+		{Type: "AWS::IAM::Role", Name: "worker", Region: globalRegion, Chain: []string{"alice", "i-web"}},
+		{Type: "AWS::S3::Bucket", Name: "results", Region: globalRegion, Chain: []string{"alice", "i-web", "assumed-role/worker"}},
+		{Type: "AWS::EC2::Volume", Name: "vol-0123", Region: "us-east-1", Chain: []string{"alice"}},
+		// Found without recursion
+		{Type: "AWS::EC2::KeyPair", Name: "bob", Region: "us-east-1", Principal: "bob"},
+	}
+
+	var lines []string
+	var walk func(nodes []*ProvenanceNode, indent string)
+	walk = func(nodes []*ProvenanceNode, indent string) {
+		for _, node := range nodes {
+			line := indent + node.Principal
+			if node.Resource != nil {
+				line += fmt.Sprintf("[%s]", node.Resource.Name)
+			}
+			lines = append(lines, line)
+			walk(node.Children, indent+"  ")
+		}
+	}
+	walk(provenanceTree(resources), "")
+
+	want := `alice
+  i-web[i-web]
+    assumed-role/worker[worker]
+      [results]
+  [vol-0123]
+bob
+  [bob]`
+	if got := strings.Join(lines, "\n"); got != want {
+		t.Errorf("got\n%s\nwant\n%s", got, want)
+	}
+}
@@ -0,0 +1,19 @@
+package main
+
+import (
+	"github.com/aws/aws-sdk-go/service/route53"
+)
+
+var svcRoute53 *route53.Route53
+
+// route53Client returns the Route53 client. Route53 is global, it uses the
+// main session.
+func route53Client() *route53.Route53 {
+	clientsMutex.Lock()
+	defer clientsMutex.Unlock()
+
+	if svcRoute53 == nil {
+		svcRoute53 = route53.New(sess)
+	}
+	return svcRoute53
+}
@@ -0,0 +1,245 @@
+package main
+
+import (
+	"encoding/json"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/service/cloudtrail"
+	"github.com/aws/aws-sdk-go/service/route53"
+	"strings"
+)
+
+func init() {
+	registerResourceType("AWS::Route53::HostedZone", &resourceHandler{
+		CreatedBy:    []string{"CreateHostedZone"},
+		ArnTypes:     []string{"route53:hostedzone"},
+		TrailIdKeys:  []string{"hostedZoneId"},
+		NukeType:     "Route53HostedZone",
+		Exists:       route53HostedZoneExists,
+		Describe:     route53HostedZoneDescribe,
+		Keep:         route53HostedZoneKeep,
+		Delete:       route53HostedZoneDelete,
+		DeleteScript: route53HostedZoneDeleteScript,
+		Tag:          route53HostedZoneTag,
+	})
+}
+
+// route53HostedZoneId returns the id of the hosted zone without the
+// /hostedzone/ prefix CloudTrail sometimes uses.
+func route53HostedZoneId(hostedZoneId string) string {
+	return strings.TrimPrefix(hostedZoneId, "/hostedzone/")
+}
+
+func route53HostedZoneExists(resource *Resource) (bool, error) {
+	v("exists?", resource.Name)
+	svc := route53Client()
+
+	input := &route53.GetHostedZoneInput{
+		Id: aws.String(route53HostedZoneId(resource.Name)),
+	}
+	_, err := svc.GetHostedZone(input)
+	if err != nil {
+		if aerr, ok := err.(awserr.Error); ok {
+			switch aerr.Code() {
+			case route53.ErrCodeNoSuchHostedZone:
+				return false, nil
+			}
+		}
+		return false, err
+	}
+
+	return true, nil
+}
+
+func route53HostedZoneDescribe(resource *Resource) (*ResourceDetails, error) {
+	svc := route53Client()
+
+	result, err := svc.ListTagsForResource(&route53.ListTagsForResourceInput{
+		ResourceId:   aws.String(route53HostedZoneId(resource.Name)),
+		ResourceType: aws.String(route53.TagResourceTypeHostedzone),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	tags := map[string]string{}
+	for _, tag := range result.ResourceTagSet.Tags {
+		tags[aws.StringValue(tag.Key)] = aws.StringValue(tag.Value)
+	}
+
+	return &ResourceDetails{
+		Tags: tags,
+	}, nil
+}
+
+// route53HostedZoneChanges returns the ChangeResourceRecordSets events of the
+// zone since it was created, from the -trail archive or from LookupEvents.
+func route53HostedZoneChanges(resource *Resource) ([]*trailEvent, error) {
+	hostedZoneId := route53HostedZoneId(resource.Name)
+	changes := []*trailEvent{}
+
+	if trail != nil {
+		for _, event := range trail.events {
+			if event.EventName != "ChangeResourceRecordSets" || event.EventTime.Before(resource.EventTime) {
+				continue
+			}
+			for _, id := range event.Ids {
+				if id[0] == "AWS::Route53::HostedZone" && route53HostedZoneId(id[1]) == hostedZoneId {
+					changes = append(changes, event)
+					break
+				}
+			}
+		}
+		return changes, nil
+	}
+
+	startTime := resource.EventTime
+	input := &cloudtrail.LookupEventsInput{
+		StartTime: &startTime,
+		LookupAttributes: []*cloudtrail.LookupAttribute{
+			{
+				AttributeKey:   aws.String("ResourceName"),
+				AttributeValue: aws.String(hostedZoneId),
+			},
+		},
+	}
+	var decodeErr error
+	err := cloudtrailClient(globalEventsRegion).LookupEventsPages(input,
+		func(page *cloudtrail.LookupEventsOutput, lastPage bool) bool {
+			for _, event := range page.Events {
+				if aws.StringValue(event.EventName) != "ChangeResourceRecordSets" {
+					continue
+				}
+				var record trailRecord
+				if decodeErr = json.Unmarshal([]byte(aws.StringValue(event.CloudTrailEvent)), &record); decodeErr != nil {
+					return false
+				}
+				changes = append(changes, newTrailEvent(&record))
+			}
+			return true
+		})
+	if err == nil {
+		err = decodeErr
+	}
+	return changes, err
+}
+
+// route53HostedZoneKeep keeps the zones the user did not create, ex: a shared
+// public zone the user only added records to, and the zones with records
+// changed by principals outside their chain, from the user to the principal
+// that created them. The zones found without event, by tags or as the private
+// zone of a cluster, are the user's when a cluster owns them.
+func route53HostedZoneKeep(resource *Resource) (string, error) {
+	if resource.EventName == "" {
+		if resource.Details != nil {
+			for key, value := range resource.Details.Tags {
+				if strings.HasPrefix(key, clusterTagPrefix) && value == "owned" {
+					return "", nil
+				}
+			}
+		}
+		return "hosted zone not owned by a cluster", nil
+	}
+	if resource.EventName != "CreateHostedZone" {
+		return "hosted zone not created by the user", nil
+	}
+
+	changes, err := route53HostedZoneChanges(resource)
+	if err != nil {
+		return "", err
+	}
+ChangeLoop:
+	for _, event := range changes {
+		for _, principal := range resource.Chain {
+			if isPrincipal(event, principal) {
+				continue ChangeLoop
+			}
+		}
+		by := event.Arn
+		if by == "" {
+			by = event.UserName
+		}
+		return "hosted zone with records changed by " + by, nil
+	}
+	return "", nil
+}
+
+func route53HostedZoneDelete(resource *Resource) error {
+	svc := route53Client()
+	hostedZoneId := route53HostedZoneId(resource.Name)
+
+	zone, err := svc.GetHostedZone(&route53.GetHostedZoneInput{
+		Id: &hostedZoneId,
+	})
+	if err != nil {
+		return err
+	}
+
+	// A hosted zone must be empty, except for its own SOA and NS records.
+	changes := []*route53.Change{}
+	err = svc.ListResourceRecordSetsPages(
+		&route53.ListResourceRecordSetsInput{
+			HostedZoneId: &hostedZoneId,
+		},
+		func(page *route53.ListResourceRecordSetsOutput, lastPage bool) bool {
+			for _, recordSet := range page.ResourceRecordSets {
+				if *recordSet.Name == *zone.HostedZone.Name &&
+					(*recordSet.Type == "SOA" || *recordSet.Type == "NS") {
+					continue
+				}
+				changes = append(changes, &route53.Change{
+					Action:            aws.String(route53.ChangeActionDelete),
+					ResourceRecordSet: recordSet,
+				})
+			}
+			return true
+		})
+	if err != nil {
+		return err
+	}
+
+	if len(changes) > 0 {
+		_, err = svc.ChangeResourceRecordSets(&route53.ChangeResourceRecordSetsInput{
+			HostedZoneId: &hostedZoneId,
+			ChangeBatch: &route53.ChangeBatch{
+				Changes: changes,
+			},
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	_, err = svc.DeleteHostedZone(&route53.DeleteHostedZoneInput{
+		Id: &hostedZoneId,
+	})
+	return err
+}
+
+// The records are deleted in one change batch, except the SOA and NS records
+// of the zone itself.
+func route53HostedZoneDeleteScript(resource *Resource, aws string) []string {
+	id := shellQuote(route53HostedZoneId(resource.Name))
+	records := `ResourceRecordSets[?!(Name=='$zone' && (Type=='SOA' || Type=='NS'))]`
+	return []string{
+		"zone=$(" + aws + " route53 get-hosted-zone --id " + id + " --query HostedZone.Name --output text)",
+		"changes=$(" + aws + " route53 list-resource-record-sets --hosted-zone-id " + id + ` --query "{Changes: ` + records + `.{Action: 'DELETE', ResourceRecordSet: @}}" --output json)`,
+		"if [ \"$(" + aws + " route53 list-resource-record-sets --hosted-zone-id " + id + ` --query "length(` + records + `)" --output text)" != 0 ]; then`,
+		"  " + aws + " route53 change-resource-record-sets --hosted-zone-id " + id + ` --change-batch "$changes"`,
+		"fi",
+		aws + " route53 delete-hosted-zone --id " + id,
+	}
+}
+
+func route53HostedZoneTag(resource *Resource, tags map[string]string) error {
+	list := []*route53.Tag{}
+	for key, value := range tags {
+		list = append(list, &route53.Tag{Key: aws.String(key), Value: aws.String(value)})
+	}
+	_, err := route53Client().ChangeTagsForResource(&route53.ChangeTagsForResourceInput{
+		ResourceId:   aws.String(route53HostedZoneId(resource.Name)),
+		ResourceType: aws.String(route53.TagResourceTypeHostedzone),
+		AddTags:      list,
+	})
+	return err
+}
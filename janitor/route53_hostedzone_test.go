@@ -0,0 +1,142 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestRoute53HostedZoneKeep(t *testing.T) {
+	tests := []struct {
+		name string
+		zone *Resource
+		kept bool
+	}{
+		{
+			name: "private zone of a cluster of the user",
+			zone: &Resource{Details: &ResourceDetails{Tags: map[string]string{"kubernetes.io/cluster/ci-op-abcd": "owned"}}},
+		},
+		{
+			name: "zone shared with a cluster",
+			zone: &Resource{Details: &ResourceDetails{Tags: map[string]string{"kubernetes.io/cluster/ci-op-abcd": "shared"}}},
+			kept: true,
+		},
+		{
+			name: "zone found by another tag",
+			zone: &Resource{Details: &ResourceDetails{Tags: map[string]string{"owner": "alice"}}},
+			kept: true,
+		},
+		{
+			name: "tags not described",
+			zone: &Resource{},
+			kept: true,
+		},
+		{
+			name: "records added by the user",
+			zone: &Resource{EventName: "ChangeResourceRecordSets", Details: &ResourceDetails{Tags: map[string]string{"kubernetes.io/cluster/ci-op-abcd": "owned"}}},
+			kept: true,
+		},
+		{
+			name: "VPC associated by the user",
+			zone: &Resource{EventName: "AssociateVPCWithHostedZone"},
+			kept: true,
+		},
+	}
+
+	for _, test := range tests {
+		test.zone.Type = "AWS::Route53::HostedZone"
+		test.zone.Name = "Z0123456789"
+		test.zone.Region = globalRegion
+		reason, err := route53HostedZoneKeep(test.zone)
+		if err != nil {
+			t.Errorf("%s: %v", test.name, err)
+			continue
+		}
+		if kept := reason != ""; kept != test.kept {
+			t.Errorf("%s: kept %v (%q), want %v", test.name, kept, reason, test.kept)
+		}
+	}
+}
+
+func TestRoute53HostedZoneId(t *testing.T) {
+	for _, name := range []string{"Z0123456789", "/hostedzone/Z0123456789"} {
+		if id := route53HostedZoneId(name); id != "Z0123456789" {
+			t.Errorf("route53HostedZoneId(%q) = %q", name, id)
+		}
+	}
+}
+
+// The records of the cluster domain in the public zone are children of the
+// private zone: deleted with it, protected with it.
+func TestClusterZoneRecords(t *testing.T) {
+	for _, value := range []string{"owned", "shared"} {
+		zone := &Resource{
+			Type:    "AWS::Route53::HostedZone",
+			Name:    "Zprivate",
+			Region:  globalRegion,
+			FoundBy: []string{backendCluster},
+			Details: &ResourceDetails{Tags: map[string]string{"kubernetes.io/cluster/ci-op-abcd": value}},
+		}
+		api := &Resource{
+			Type:       "AWS::Route53::RecordSet",
+			Name:       "Zpublic:A:api.ci-op-abcd.example.com.",
+			Region:     globalRegion,
+			FoundBy:    []string{backendCluster},
+			Parent:     "Zprivate",
+			ParentType: "AWS::Route53::HostedZone",
+		}
+
+		candidates, kept := filterKept([]*Resource{zone, api})
+		candidates, orphans := filterOrphans(candidates)
+
+		if value == "owned" {
+			if len(candidates) != 2 || len(kept) != 0 || len(orphans) != 0 {
+				t.Errorf("owned zone: %d deleted, %d kept, %d orphans, want the zone and its records deleted", len(candidates), len(kept), len(orphans))
+			}
+			continue
+		}
+		if len(candidates) != 0 || len(kept) != 1 || len(orphans) != 1 || orphans[0] != api {
+			t.Errorf("shared zone: %d deleted, %d kept, %d orphans, want the zone kept and its records orphans", len(candidates), len(kept), len(orphans))
+		}
+	}
+}
+
+func TestRoute53HostedZoneChangedByOthers(t *testing.T) {
+	defer func(archive *trailArchive) { trail = archive }(trail)
+
+	created := time.Date(2019, 1, 14, 8, 0, 0, 0, time.UTC)
+	change := func(arn string, at time.Time) *trailEvent {
+		return &trailEvent{
+			EventName: "ChangeResourceRecordSets",
+			EventTime: at,
+			Arn:       arn,
+			Ids:       [][2]string{{"AWS::Route53::HostedZone", "/hostedzone/Z0123456789"}},
+		}
+	}
+	zone := &Resource{
+		Type:      "AWS::Route53::HostedZone",
+		Name:      "Z0123456789",
+		Region:    globalRegion,
+		EventName: "CreateHostedZone",
+		EventTime: created,
+		Chain:     []string{"alice", "i-0123"},
+	}
+
+	trail = &trailArchive{events: []*trailEvent{
+		change("arn:aws:iam::123456789012:user/alice", created.Add(time.Minute)),
+		change("arn:aws:sts::123456789012:assumed-role/installer/i-0123", created.Add(time.Hour)),
+		// Before the zone was created, ex: a previous zone of the same id
+		change("arn:aws:iam::123456789012:user/bob", created.Add(-time.Hour)),
+	}}
+	if reason, err := route53HostedZoneKeep(zone); err != nil || reason != "" {
+		t.Errorf("zone changed by the user and its instance kept: %q, %v", reason, err)
+	}
+
+	trail.events = append(trail.events, change("arn:aws:iam::123456789012:user/bob", created.Add(2*time.Hour)))
+	reason, err := route53HostedZoneKeep(zone)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "hosted zone with records changed by arn:aws:iam::123456789012:user/bob"; reason != want {
+		t.Errorf("got %q, want %q", reason, want)
+	}
+}
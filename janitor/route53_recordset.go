@@ -0,0 +1,109 @@
+package main
+
+import (
+	"fmt"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/service/route53"
+	"strings"
+)
+
+// Record sets are not reported by CloudTrail as resources, janitor names them
+// zone-id:type:name, ex: Z0123456789:A:api.cluster.example.com.
+// They are found in the public zones of the clusters, see cluster.go.
+func init() {
+	registerResourceType("AWS::Route53::RecordSet", &resourceHandler{
+		NukeType:     "Route53ResourceRecordSet",
+		Exists:       route53RecordSetExists,
+		Delete:       route53RecordSetDelete,
+		DeleteScript: route53RecordSetDeleteScript,
+	})
+}
+
+func route53RecordSetName(zoneId string, recordType string, name string) string {
+	return route53HostedZoneId(zoneId) + ":" + recordType + ":" + name
+}
+
+func parseRoute53RecordSetName(recordSet string) (zoneId string, recordType string, name string, err error) {
+	parts := strings.SplitN(recordSet, ":", 3)
+	if len(parts) != 3 {
+		return "", "", "", fmt.Errorf("bad record set %s, expected zone-id:type:name", recordSet)
+	}
+	return parts[0], parts[1], parts[2], nil
+}
+
+// route53RecordSets returns the record sets of the name and type, several
+// with weighted or latency records.
+func route53RecordSets(recordSet string) ([]*route53.ResourceRecordSet, string, error) {
+	zoneId, recordType, name, err := parseRoute53RecordSetName(recordSet)
+	if err != nil {
+		return nil, "", err
+	}
+
+	result := []*route53.ResourceRecordSet{}
+	err = route53Client().ListResourceRecordSetsPages(
+		&route53.ListResourceRecordSetsInput{
+			HostedZoneId:    aws.String(zoneId),
+			StartRecordName: aws.String(name),
+			StartRecordType: aws.String(recordType),
+		},
+		func(page *route53.ListResourceRecordSetsOutput, lastPage bool) bool {
+			for _, set := range page.ResourceRecordSets {
+				if aws.StringValue(set.Name) != name || aws.StringValue(set.Type) != recordType {
+					// Sets are sorted by name and type, the others follow
+					return false
+				}
+				result = append(result, set)
+			}
+			return true
+		})
+	return result, zoneId, err
+}
+
+func route53RecordSetExists(resource *Resource) (bool, error) {
+	v("exists?", resource.Name)
+
+	sets, _, err := route53RecordSets(resource.Name)
+	if err != nil {
+		if aerr, ok := err.(awserr.Error); ok && aerr.Code() == route53.ErrCodeNoSuchHostedZone {
+			return false, nil
+		}
+		return false, err
+	}
+	return len(sets) > 0, nil
+}
+
+func route53RecordSetDelete(resource *Resource) error {
+	sets, zoneId, err := route53RecordSets(resource.Name)
+	if err != nil || len(sets) == 0 {
+		return err
+	}
+
+	changes := []*route53.Change{}
+	for _, set := range sets {
+		changes = append(changes, &route53.Change{
+			Action:            aws.String(route53.ChangeActionDelete),
+			ResourceRecordSet: set,
+		})
+	}
+	_, err = route53Client().ChangeResourceRecordSets(&route53.ChangeResourceRecordSetsInput{
+		HostedZoneId: aws.String(zoneId),
+		ChangeBatch: &route53.ChangeBatch{
+			Changes: changes,
+		},
+	})
+	return err
+}
+
+func route53RecordSetDeleteScript(resource *Resource, aws string) []string {
+	zoneId, recordType, name, err := parseRoute53RecordSetName(resource.Name)
+	if err != nil {
+		return []string{"# " + err.Error()}
+	}
+	id := shellQuote(zoneId)
+	return []string{
+		"changes=$(" + aws + " route53 list-resource-record-sets --hosted-zone-id " + id +
+			` --query "{Changes: ResourceRecordSets[?Name=='` + name + `' && Type=='` + recordType + `'].{Action: 'DELETE', ResourceRecordSet: @}}" --output json)`,
+		aws + " route53 change-resource-record-sets --hosted-zone-id " + id + ` --change-batch "$changes"`,
+	}
+}
@@ -2,38 +2,49 @@ package main
 
 import (
 	"github.com/aws/aws-sdk-go/aws"
-	"github.com/aws/aws-sdk-go/aws/awserr"
 	"github.com/aws/aws-sdk-go/service/s3"
+	"github.com/aws/aws-sdk-go/service/s3/s3manager"
 )
 
-var svcS3 *s3.S3
+var svcS3 = map[string]*s3.S3{}
 
-func s3BucketExists(bucketId string) bool {
-	v("exists?", bucketId)
-	if svcS3 == nil {
-		svcS3 = s3.New(sess)
+// Regions by bucket, looked up once
+var s3Regions = map[string]string{}
+
+// s3BucketRegion returns the region of a bucket.
+func s3BucketRegion(bucketId string) (string, error) {
+	clientsMutex.Lock()
+	region, ok := s3Regions[bucketId]
+	clientsMutex.Unlock()
+	if ok {
+		return region, nil
 	}
 
-	input := &s3.HeadBucketInput{
-		Bucket: aws.String(bucketId),
+	region, err := s3manager.GetBucketRegion(aws.BackgroundContext(), sess, bucketId, defaultRegion)
+	if err != nil {
+		return "", err
 	}
-	_, err := svcS3.HeadBucket(input)
+
+	clientsMutex.Lock()
+	defer clientsMutex.Unlock()
+	s3Regions[bucketId] = region
+	return region, nil
+}
+
+// s3Client returns a client for the region the bucket lives in.
+// Buckets are global, so their region is not known from the CloudTrail
+// lookup that found them.
+func s3Client(bucketId string) (*s3.S3, error) {
+	region, err := s3BucketRegion(bucketId)
 	if err != nil {
-		if aerr, ok := err.(awserr.Error); ok {
-			switch aerr.Code() {
-			case "NotFound":
-				return false
-			case s3.ErrCodeNoSuchBucket:
-				return false
-			default:
-				logErr.Println(aerr.Error())
-				return false
-			}
-		} else {
-			logErr.Println(err.Error())
-			return false
-		}
+		return nil, err
 	}
 
-	return false
+	clientsMutex.Lock()
+	defer clientsMutex.Unlock()
+
+	if svcS3[region] == nil {
+		svcS3[region] = s3.New(regionSession(region))
+	}
+	return svcS3[region], nil
 }
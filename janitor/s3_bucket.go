@@ -0,0 +1,178 @@
+package main
+
+import (
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/service/s3"
+)
+
+func init() {
+	registerResourceType("AWS::S3::Bucket", &resourceHandler{
+		CreatedBy:    []string{"CreateBucket"},
+		ArnTypes:     []string{"s3:"},
+		TrailIdKeys:  []string{"bucketName"},
+		NukeType:     "S3Bucket",
+		Exists:       s3BucketExists,
+		Describe:     s3BucketDescribe,
+		Delete:       s3BucketDelete,
+		DeleteScript: s3BucketDeleteScript,
+		Tag:          s3BucketTag,
+	})
+}
+
+func s3BucketExists(resource *Resource) (bool, error) {
+	v("exists?", resource.Name)
+
+	svc, err := s3Client(resource.Name)
+	if err == nil {
+		input := &s3.HeadBucketInput{
+			Bucket: aws.String(resource.Name),
+		}
+		_, err = svc.HeadBucket(input)
+	}
+	if err != nil {
+		if aerr, ok := err.(awserr.Error); ok {
+			switch aerr.Code() {
+			case "NotFound":
+				return false, nil
+			case s3.ErrCodeNoSuchBucket:
+				return false, nil
+			}
+		}
+		return false, err
+	}
+
+	return true, nil
+}
+
+func s3BucketDescribe(resource *Resource) (*ResourceDetails, error) {
+	svc, err := s3Client(resource.Name)
+	if err != nil {
+		return nil, err
+	}
+
+	details := &ResourceDetails{
+		Tags: map[string]string{},
+	}
+
+	tagging, err := svc.GetBucketTagging(&s3.GetBucketTaggingInput{
+		Bucket: &resource.Name,
+	})
+	if err != nil {
+		// A bucket without tags returns NoSuchTagSet
+		if aerr, ok := err.(awserr.Error); !ok || aerr.Code() != "NoSuchTagSet" {
+			return nil, err
+		}
+	} else {
+		for _, tag := range tagging.TagSet {
+			details.Tags[aws.StringValue(tag.Key)] = aws.StringValue(tag.Value)
+		}
+	}
+
+	buckets, err := svc.ListBuckets(&s3.ListBucketsInput{})
+	if err != nil {
+		return nil, err
+	}
+	for _, bucket := range buckets.Buckets {
+		if aws.StringValue(bucket.Name) == resource.Name {
+			details.CreationTime = bucket.CreationDate
+			details.Owner = aws.StringValue(buckets.Owner.DisplayName)
+		}
+	}
+
+	return details, nil
+}
+
+func s3BucketDelete(resource *Resource) error {
+	svc, err := s3Client(resource.Name)
+	if err != nil {
+		return err
+	}
+
+	// A bucket must be empty, including old versions and delete markers,
+	// before it can be deleted.
+	err = svc.ListObjectVersionsPages(
+		&s3.ListObjectVersionsInput{
+			Bucket: aws.String(resource.Name),
+		},
+		func(page *s3.ListObjectVersionsOutput, lastPage bool) bool {
+			objects := []*s3.ObjectIdentifier{}
+			for _, version := range page.Versions {
+				objects = append(objects, &s3.ObjectIdentifier{
+					Key:       version.Key,
+					VersionId: version.VersionId,
+				})
+			}
+			for _, marker := range page.DeleteMarkers {
+				objects = append(objects, &s3.ObjectIdentifier{
+					Key:       marker.Key,
+					VersionId: marker.VersionId,
+				})
+			}
+			if len(objects) == 0 {
+				return true
+			}
+
+			_, err := svc.DeleteObjects(&s3.DeleteObjectsInput{
+				Bucket: aws.String(resource.Name),
+				Delete: &s3.Delete{
+					Objects: objects,
+					Quiet:   aws.Bool(true),
+				},
+			})
+			if err != nil {
+				logErr.Println("Got error emptying bucket", resource.Name)
+				logErr.Println(err.Error())
+				return false
+			}
+			return true
+		})
+	if err != nil {
+		return err
+	}
+
+	_, err = svc.DeleteBucket(&s3.DeleteBucketInput{
+		Bucket: aws.String(resource.Name),
+	})
+	return err
+}
+
+// s3 rb --force deletes the current objects only, the old versions and
+// delete markers of a versioned bucket must be deleted first.
+func s3BucketDeleteScript(resource *Resource, aws string) []string {
+	bucket := shellQuote(resource.Name)
+	versions := aws + " s3api list-object-versions --bucket " + bucket + " --max-items 500"
+	return []string{
+		"# Versions and delete markers first, rb only deletes the current objects",
+		"while [ \"$(" + versions + " --query 'length([Versions, DeleteMarkers][])' --output text)\" != 0 ]; do",
+		"  " + aws + " s3api delete-objects --bucket " + bucket + " --delete \"$(" + versions + " --query '{Objects: [Versions, DeleteMarkers][].{Key: Key, VersionId: VersionId}, Quiet: `true`}' --output json)\" >/dev/null",
+		"done",
+		aws + " s3 rb --force " + shellQuote("s3://"+resource.Name),
+	}
+}
+
+// PutBucketTagging replaces all the tags of the bucket, the new tags are
+// merged into the current ones.
+func s3BucketTag(resource *Resource, tags map[string]string) error {
+	details, err := s3BucketDescribe(resource)
+	if err != nil {
+		return err
+	}
+	svc, err := s3Client(resource.Name)
+	if err != nil {
+		return err
+	}
+
+	for key, value := range tags {
+		details.Tags[key] = value
+	}
+	list := []*s3.Tag{}
+	for key, value := range details.Tags {
+		list = append(list, &s3.Tag{Key: aws.String(key), Value: aws.String(value)})
+	}
+	_, err = svc.PutBucketTagging(&s3.PutBucketTaggingInput{
+		Bucket:  &resource.Name,
+		Tagging: &s3.Tagging{TagSet: list},
+	})
+	return err
+}
@@ -0,0 +1,120 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+)
+
+// shellQuote quotes a value for sh.
+func shellQuote(value string) string {
+	return "'" + strings.Replace(value, "'", `'\''`, -1) + "'"
+}
+
+// scriptCommand returns a DeleteScript running a single aws CLI command
+// ending with the id of the resource, ex: "ec2 delete-volume --volume-id".
+func scriptCommand(command string) func(resource *Resource, aws string) []string {
+	return func(resource *Resource, aws string) []string {
+		return []string{aws + " " + command + " " + shellQuote(resource.Name)}
+	}
+}
+
+// scriptAws returns the aws CLI command with the region of the resource,
+// the default region for global resources, and profile, if any.
+func scriptAws(resource *Resource, profile string) string {
+	region := resource.Region
+	if region == globalRegion {
+		region = defaultRegion
+	}
+	command := "aws --region " + shellQuote(region)
+	if profile != "" {
+		command += " --profile " + shellQuote(profile)
+	}
+	return command
+}
+
+// scriptAssumeRole defines assume_role, which exports the credentials of a
+// role assumed with the credentials the script runs with. Each account is
+// deleted in a subshell, so that the next one assumes its role from them.
+var scriptAssumeRole = []string{
+	"",
+	"# assume_role exports the credentials of the role of an account",
+	"assume_role() {",
+	"  credentials=$(aws sts assume-role --role-arn \"$1\" --role-session-name janitor --query 'Credentials.[AccessKeyId, SecretAccessKey, SessionToken]' --output text)",
+	"  set -- $credentials",
+	"  export AWS_ACCESS_KEY_ID=\"$1\" AWS_SECRET_ACCESS_KEY=\"$2\" AWS_SESSION_TOKEN=\"$3\"",
+	"  unset AWS_PROFILE",
+	"}",
+}
+
+// writeScript writes a shell script deleting the resources of the reports in
+// dependency order, with the aws CLI. Each resource is preceded by a comment
+// naming the CloudTrail event that introduced it, so that an operator can
+// review and edit the script before running it.
+func writeScript(w io.Writer, reports []*Report) error {
+	lines := []string{
+		"#!/bin/sh",
+		"# Generated by janitor on " + time.Now().UTC().Format(time.RFC3339) + ".",
+		"# Review and edit before running: the commands run in dependency order,",
+		"# the script stops at the first error.",
+		"set -eu",
+	}
+	for _, report := range reports {
+		if report.RoleArn != "" {
+			lines = append(lines, scriptAssumeRole...)
+			break
+		}
+	}
+
+	for _, report := range reports {
+		lines = append(lines, "")
+		// The profile janitor runs with, or the role of the account
+		profile := os.Getenv("AWS_PROFILE")
+		if report.RoleArn != "" {
+			profile = ""
+			lines = append(lines, "# Account "+report.Account+", through its role", "(", "assume_role "+shellQuote(report.RoleArn))
+		} else if report.Account != "" {
+			lines = append(lines, "# Account "+report.Account+", run with credentials of this account")
+		}
+		for _, user := range report.Users {
+			lines = append(lines, "# Resources of "+user.User+" since "+user.StartTime.Format(time.RFC3339))
+		}
+		if len(report.Users) == 0 {
+			lines = append(lines, "# Resources of "+report.User+" since "+report.StartTime.Format(time.RFC3339))
+		}
+
+		setDeleteSet(report.Resources)
+		for i, pass := range deleteOrder(report.Resources) {
+			lines = append(lines, "", fmt.Sprintf("# Pass %d", i+1))
+			for _, resource := range pass {
+				lines = append(lines, "")
+				lines = append(lines, "# "+resource.Type+" "+resource.Name+" ("+resource.Region+")")
+				if resource.EventName != "" {
+					event := "# " + resource.EventName + " " + resource.EventTime.Format(time.RFC3339) + " by " + strings.Join(resource.Chain, " > ")
+					if resource.EventID != "" {
+						event += ", event " + resource.EventID
+					}
+					lines = append(lines, event)
+				}
+				if len(resource.FoundBy) > 0 && resource.EventName == "" {
+					lines = append(lines, "# Found by "+strings.Join(resource.FoundBy, ", "))
+				}
+
+				handler, ok := resourceHandlers[resource.Type]
+				if !ok || handler.DeleteScript == nil {
+					lines = append(lines, "# No delete command for this type, delete it manually")
+					continue
+				}
+				lines = append(lines, handler.DeleteScript(resource, scriptAws(resource, profile))...)
+			}
+		}
+		if report.RoleArn != "" {
+			lines = append(lines, ")")
+		}
+	}
+
+	_, err := io.WriteString(w, strings.Join(lines, "\n")+"\n")
+	return err
+}
@@ -0,0 +1,115 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestWriteScript(t *testing.T) {
+	defer os.Setenv("AWS_PROFILE", os.Getenv("AWS_PROFILE"))
+	os.Setenv("AWS_PROFILE", "sandbox")
+	defer func(region string) { defaultRegion = region }(defaultRegion)
+	defaultRegion = "us-east-1"
+
+	start := time.Date(2019, 1, 14, 7, 4, 25, 0, time.UTC)
+	reports := []*Report{
+		{
+			Account:   "123456789012",
+			User:      "alice",
+			StartTime: start,
+			Resources: []*Resource{{Type: "AWS::S3::Bucket", Name: "alice's-logs", Region: globalRegion, FoundBy: []string{backendTags}}},
+		},
+		{
+			Account:   "210987654321",
+			RoleArn:   "arn:aws:iam::210987654321:role/OrganizationAccountAccessRole",
+			User:      "alice",
+			StartTime: start,
+			Resources: []*Resource{{
+				Type:      "AWS::EC2::Volume",
+				Name:      "vol-0123",
+				Region:    "eu-west-1",
+				EventName: "CreateVolume",
+				EventTime: start.Add(time.Hour),
+				EventID:   "6f1c0a2e",
+				Chain:     []string{"alice", "i-0123"},
+			}},
+		},
+	}
+
+	var out bytes.Buffer
+	if err := writeScript(&out, reports); err != nil {
+		t.Fatal(err)
+	}
+	script := out.String()
+
+	if strings.Count(script, "assume_role() {") != 1 {
+		t.Error("assume_role is not defined once")
+	}
+	// The account of the environment keeps the profile
+	own := script[strings.Index(script, "# Account 123456789012"):strings.Index(script, "# Account 210987654321")]
+	for _, want := range []string{
+		`--bucket 'alice'\''s-logs' --max-items 500 --query 'length([Versions, DeleteMarkers][])'`,
+		"aws --region 'us-east-1' --profile 'sandbox' s3api delete-objects --bucket 'alice'\\''s-logs'",
+		"aws --region 'us-east-1' --profile 'sandbox' s3 rb --force 's3://alice'\\''s-logs'",
+	} {
+		if !strings.Contains(own, want) {
+			t.Errorf("account of the environment lacks %q", want)
+		}
+	}
+	if strings.Index(own, "delete-objects") > strings.Index(own, "s3 rb") {
+		t.Error("bucket removed before its versions")
+	}
+
+	// The other account runs in a subshell, with the credentials of its role
+	other := script[strings.Index(script, "# Account 210987654321"):]
+	for _, want := range []string{
+		"(\nassume_role 'arn:aws:iam::210987654321:role/OrganizationAccountAccessRole'\n",
+		"# CreateVolume 2019-01-14T08:04:25Z by alice > i-0123, event 6f1c0a2e\n",
+		"aws --region 'eu-west-1' ec2 delete-volume --volume-id 'vol-0123'\n)\n",
+	} {
+		if !strings.Contains(other, want) {
+			t.Errorf("account of the role lacks %q", want)
+		}
+	}
+	if strings.Contains(other, "--profile") {
+		t.Error("the profile overrides the credentials of the role")
+	}
+}
+
+// A security group of the script is deleted like with -delete: the rules of
+// the other groups of the script referencing it are revoked when it is not
+// in use.
+func TestWriteScriptSecurityGroups(t *testing.T) {
+	defer os.Setenv("AWS_PROFILE", os.Getenv("AWS_PROFILE"))
+	os.Unsetenv("AWS_PROFILE")
+
+	reports := []*Report{{
+		Account: "123456789012",
+		User:    "alice",
+		Resources: []*Resource{
+			{Type: "AWS::EC2::SecurityGroup", Name: "sg-a", Region: "us-east-1"},
+			{Type: "AWS::EC2::SecurityGroup", Name: "sg-b", Region: "us-east-1"},
+		},
+	}}
+
+	var out bytes.Buffer
+	if err := writeScript(&out, reports); err != nil {
+		t.Fatal(err)
+	}
+	script := out.String()
+
+	for _, want := range []string{
+		"if ! aws --region 'us-east-1' ec2 delete-security-group --group-id 'sg-a'; then",
+		"  for group in 'sg-b'; do",
+		"  for group in 'sg-a'; do",
+		"SecurityGroupRules[?ReferencedGroupInfo.GroupId=='sg-a' && !IsEgress].SecurityGroupRuleId",
+		`revoke-security-group-egress --group-id "$group" --security-group-rule-ids $rules`,
+	} {
+		if !strings.Contains(script, want) {
+			t.Errorf("script lacks %q:\n%s", want, script)
+		}
+	}
+}
@@ -0,0 +1,34 @@
+package main
+
+import (
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/sts"
+)
+
+var svcSts *sts.STS
+var accountId string
+
+// stsClient returns the STS client, it uses the main session.
+func stsClient() *sts.STS {
+	clientsMutex.Lock()
+	defer clientsMutex.Unlock()
+
+	if svcSts == nil {
+		svcSts = sts.New(sess)
+	}
+	return svcSts
+}
+
+// callerAccount returns the id of the account the credentials belong to.
+func callerAccount() (string, error) {
+	if accountId != "" {
+		return accountId, nil
+	}
+
+	result, err := stsClient().GetCallerIdentity(&sts.GetCallerIdentityInput{})
+	if err != nil {
+		return "", err
+	}
+	accountId = aws.StringValue(result.Account)
+	return accountId, nil
+}
@@ -0,0 +1,124 @@
+package main
+
+import (
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/arn"
+	"github.com/aws/aws-sdk-go/service/resourcegroupstaggingapi"
+	"strings"
+)
+
+// Names of the discovery backends, reported in Resource.FoundBy
+const (
+	backendCloudtrail = "cloudtrail"
+	backendTags       = "tags"
+)
+
+var svcTagging = map[string]*resourcegroupstaggingapi.ResourceGroupsTaggingAPI{}
+
+func taggingClient(region string) *resourcegroupstaggingapi.ResourceGroupsTaggingAPI {
+	clientsMutex.Lock()
+	defer clientsMutex.Unlock()
+
+	if svcTagging[region] == nil {
+		svcTagging[region] = resourcegroupstaggingapi.New(regionSession(region))
+	}
+	return svcTagging[region]
+}
+
+// arnHandler returns the resource type and the handler of an ARN type, ex:
+// ec2:instance, see resourceHandler.ArnTypes.
+func arnHandler(key string) (string, *resourceHandler) {
+	for resourceType, handler := range resourceHandlers {
+		if contains(handler.ArnTypes, key) {
+			return resourceType, handler
+		}
+	}
+	return "", nil
+}
+
+// arnResource returns the type and the name of the resource of an ARN, as
+// CloudTrail reports them. ARNs of unknown types are returned with a
+// service:type type, they are reported as not supported.
+func arnResource(resourceArn string) (string, string) {
+	parsed, err := arn.Parse(resourceArn)
+	if err != nil {
+		return "", resourceArn
+	}
+
+	// ex: instance/i-0123, loadbalancer/app/name/id, role/path/name, bucket
+	parts := strings.Split(parsed.Resource, "/")
+	key := parsed.Service + ":"
+	if len(parts) > 1 {
+		key += parts[0]
+		// ELBv2 load balancers: loadbalancer/app/... or loadbalancer/net/...
+		if _, handler := arnHandler(key + "/" + parts[1]); handler != nil {
+			key += "/" + parts[1]
+		}
+	}
+
+	resourceType, handler := arnHandler(key)
+	if handler == nil {
+		return key, resourceArn
+	}
+	if handler.ByArn {
+		return resourceType, resourceArn
+	}
+	return resourceType, parts[len(parts)-1]
+}
+
+// parseTagFilters parses key=value tags. A tag without value matches any
+// value.
+func parseTagFilters(tags []string) []*resourcegroupstaggingapi.TagFilter {
+	filters := []*resourcegroupstaggingapi.TagFilter{}
+	for _, tag := range tags {
+		filter := &resourcegroupstaggingapi.TagFilter{}
+		if i := strings.Index(tag, "="); i >= 0 {
+			filter.Key = aws.String(tag[:i])
+			filter.Values = []*string{aws.String(tag[i+1:])}
+		} else {
+			filter.Key = aws.String(tag)
+		}
+		filters = append(filters, filter)
+	}
+	return filters
+}
+
+// searchTaggedResources returns the resources of a region having all the
+// tags, with the Resource Groups Tagging API.
+func searchTaggedResources(region string, tags []string) []*Resource {
+	v("searchTaggedResources(", region, ",", tags, ")")
+	svc := taggingClient(region)
+	resources := []*Resource{}
+	principal := "tag:" + strings.Join(tags, ",")
+
+	input := &resourcegroupstaggingapi.GetResourcesInput{
+		TagFilters: parseTagFilters(tags),
+	}
+	err := svc.GetResourcesPages(input,
+		func(page *resourcegroupstaggingapi.GetResourcesOutput, lastPage bool) bool {
+			for _, mapping := range page.ResourceTagMappingList {
+				resourceType, name := arnResource(aws.StringValue(mapping.ResourceARN))
+				resourceRegion := region
+				if isGlobalType(resourceType) {
+					resourceRegion = globalRegion
+				}
+				resources = append(resources, &Resource{
+					Type:      resourceType,
+					Name:      name,
+					Region:    resourceRegion,
+					Principal: principal,
+					Chain:     []string{principal},
+					FoundBy:   []string{backendTags},
+				})
+				v("└──", resourceRegion, resourceType, name)
+			}
+			return true
+		})
+	if err != nil {
+		logErr.Println("Got error calling GetResources:")
+		logErr.Println(err.Error())
+		searchFailed("GetResources in "+region, err)
+	}
+
+	return resources
+}
@@ -0,0 +1,81 @@
+package main
+
+import (
+	"testing"
+)
+
+func TestArnResource(t *testing.T) {
+	tests := []struct {
+		arn      string
+		wantType string
+		wantName string
+	}{
+		{"arn:aws:ec2:us-east-1:123456789012:instance/i-0123", "AWS::EC2::Instance", "i-0123"},
+		{"arn:aws:ec2:us-east-1:123456789012:elastic-ip/eipalloc-0123", "AWS::EC2::EIP", "eipalloc-0123"},
+		{"arn:aws:ec2:us-east-1:123456789012:security-group/sg-0123", "AWS::EC2::SecurityGroup", "sg-0123"},
+		{"arn:aws:ec2:us-east-1::image/ami-0123", "AWS::EC2::Ami", "ami-0123"},
+		{
+			"arn:aws:elasticloadbalancing:us-east-1:123456789012:loadbalancer/classic",
+			"AWS::ElasticLoadBalancing::LoadBalancer", "classic",
+		},
+		{
+			"arn:aws:elasticloadbalancing:us-east-1:123456789012:loadbalancer/app/web/0123",
+			"AWS::ElasticLoadBalancingV2::LoadBalancer",
+			"arn:aws:elasticloadbalancing:us-east-1:123456789012:loadbalancer/app/web/0123",
+		},
+		{
+			"arn:aws:elasticloadbalancing:us-east-1:123456789012:loadbalancer/net/api/0123",
+			"AWS::ElasticLoadBalancingV2::LoadBalancer",
+			"arn:aws:elasticloadbalancing:us-east-1:123456789012:loadbalancer/net/api/0123",
+		},
+		{
+			"arn:aws:elasticloadbalancing:us-east-1:123456789012:targetgroup/web/0123",
+			"AWS::ElasticLoadBalancingV2::TargetGroup",
+			"arn:aws:elasticloadbalancing:us-east-1:123456789012:targetgroup/web/0123",
+		},
+		{"arn:aws:iam::123456789012:role/path/name", "AWS::IAM::Role", "name"},
+		{"arn:aws:iam::123456789012:instance-profile/name", "AWS::IAM::InstanceProfile", "name"},
+		{"arn:aws:iam::123456789012:policy/name", "AWS::IAM::Policy", "arn:aws:iam::123456789012:policy/name"},
+		{"arn:aws:route53:::hostedzone/Z0123", "AWS::Route53::HostedZone", "Z0123"},
+		{"arn:aws:s3:::bucket", "AWS::S3::Bucket", "bucket"},
+		{"arn:aws:sqs:us-east-1:123456789012:queue", "sqs:", "arn:aws:sqs:us-east-1:123456789012:queue"},
+		{"arn:aws:ec2:us-east-1:123456789012:transit-gateway/tgw-0123", "ec2:transit-gateway", "arn:aws:ec2:us-east-1:123456789012:transit-gateway/tgw-0123"},
+		{"not-an-arn", "", "not-an-arn"},
+	}
+
+	for _, test := range tests {
+		gotType, gotName := arnResource(test.arn)
+		if gotType != test.wantType || gotName != test.wantName {
+			t.Errorf("arnResource(%s) = %s, %s, want %s, %s", test.arn, gotType, gotName, test.wantType, test.wantName)
+		}
+	}
+}
+
+// TestRegistryNames checks that the ARN types, trail id keys and aws-nuke
+// types of the handlers name a single resource type.
+func TestRegistryNames(t *testing.T) {
+	arnTypes := map[string]string{}
+	trailIdKeys := map[string]string{}
+	nukeTypes := map[string]string{}
+
+	for resourceType, handler := range resourceHandlers {
+		for _, arnType := range handler.ArnTypes {
+			if other, ok := arnTypes[arnType]; ok {
+				t.Errorf("ARN type %s is both %s and %s", arnType, other, resourceType)
+			}
+			arnTypes[arnType] = resourceType
+		}
+		for _, key := range handler.TrailIdKeys {
+			if other, ok := trailIdKeys[key]; ok {
+				t.Errorf("trail id key %s is both %s and %s", key, other, resourceType)
+			}
+			trailIdKeys[key] = resourceType
+		}
+		if handler.NukeType != "" {
+			if other, ok := nukeTypes[handler.NukeType]; ok {
+				t.Errorf("aws-nuke type %s is both %s and %s", handler.NukeType, other, resourceType)
+			}
+			nukeTypes[handler.NukeType] = resourceType
+		}
+	}
+}
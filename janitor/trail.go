@@ -0,0 +1,587 @@
+package main
+
+import (
+	"compress/gzip"
+	"encoding/json"
+	"fmt"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/s3"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// trailRecord is an event of a CloudTrail log file. Only the fields needed
+// to find resources are decoded.
+type trailRecord struct {
+	EventName   string    `json:"eventName"`
+	EventTime   time.Time `json:"eventTime"`
+	EventID     string    `json:"eventID"`
+	EventSource string    `json:"eventSource"`
+	AwsRegion   string    `json:"awsRegion"`
+	// RecipientAccountId is the account of the event, organization
+	// trails archive the events of all the accounts
+	RecipientAccountId string `json:"recipientAccountId"`
+	ReadOnly           *bool  `json:"readOnly"`
+	ErrorCode          string `json:"errorCode"`
+	UserIdentity       struct {
+		UserName    string `json:"userName"`
+		Arn         string `json:"arn"`
+		AccessKeyId string `json:"accessKeyId"`
+	} `json:"userIdentity"`
+	RequestParameters interface{} `json:"requestParameters"`
+	ResponseElements  interface{} `json:"responseElements"`
+}
+
+// trailEvent is what the archive keeps of a record: the fields principals
+// and event rules match, and the resources it references.
+type trailEvent struct {
+	EventName   string
+	EventTime   time.Time
+	EventID     string
+	EventSource string
+	AwsRegion   string
+	ReadOnly    *bool
+	UserName    string
+	Arn         string
+	AccessKeyId string
+	// Ids are the type and name of the resources referenced, see recordIds
+	Ids [][2]string
+}
+
+func newTrailEvent(record *trailRecord) *trailEvent {
+	return &trailEvent{
+		EventName:   record.EventName,
+		EventTime:   record.EventTime,
+		EventID:     record.EventID,
+		EventSource: record.EventSource,
+		AwsRegion:   record.AwsRegion,
+		ReadOnly:    record.ReadOnly,
+		UserName:    record.UserIdentity.UserName,
+		Arn:         record.UserIdentity.Arn,
+		AccessKeyId: record.UserIdentity.AccessKeyId,
+		Ids:         recordIds(record),
+	}
+}
+
+// Date of a log file, from its key: AWSLogs/<account>/CloudTrail/<region>/YYYY/MM/DD/...
+var trailDateRegexp = regexp.MustCompile(`/(\d{4}/\d{2}/\d{2})/`)
+
+// Date of a directory of log files: YYYY, YYYY/MM or YYYY/MM/DD
+var trailDateDirRegexp = regexp.MustCompile(`(?:^|/)(\d{4}(?:/\d{2}(?:/\d{2})?)?)$`)
+
+// Account of a log file, from its key: AWSLogs/<account>/... or
+// AWSLogs/<organization id>/<account>/... for organization trails
+var trailAccountRegexp = regexp.MustCompile(`AWSLogs/(?:o-[a-z0-9]+/)?(\d{12})/`)
+
+// trailArchive is the archive of -trail, read once for the account being
+// audited. The events of a principal are looked up in memory.
+type trailArchive struct {
+	// events are the events referencing resources, for the -lookup
+	// attributes other than principals
+	events []*trailEvent
+	// byPrincipal are the events by the principals they may match: user
+	// name, ARN, ends of the ARN after a /, ex: the session name, and
+	// assumed-role/<role> for role sessions
+	byPrincipal map[string][]*trailEvent
+}
+
+var trail *trailArchive
+
+// eventResources returns the type and name of the resources introduced by
+// an event: the resources it references that it creates, ex: the instances
+// of RunInstances but not their subnet.
+func eventResources(event *trailEvent) [][2]string {
+	result := [][2]string{}
+	for _, resource := range event.Ids {
+		if isCreateEvent(resource[0], event.EventName) {
+			result = append(result, resource)
+		}
+	}
+	return result
+}
+
+// recordIds returns the type and name of the resources referenced by a
+// record, found by walking its request parameters and response elements.
+func recordIds(record *trailRecord) [][2]string {
+	result := [][2]string{}
+
+	var walk func(value interface{})
+	walk = func(value interface{}) {
+		switch value := value.(type) {
+		case map[string]interface{}:
+			for key, child := range value {
+				if name, ok := child.(string); ok {
+					if resourceType := trailIdKeyType(key); resourceType != "" {
+						result = append(result, [2]string{resourceType, name})
+					}
+					// EIPs are reported by public ip, which is also an
+					// attribute of instances and ENIs
+					if key == "publicIp" && strings.HasSuffix(record.EventName, "Address") {
+						result = append(result, [2]string{"AWS::EC2::EIP", name})
+					}
+					if key == "associationId" && strings.HasPrefix(name, "rtbassoc-") {
+						result = append(result, [2]string{"AWS::EC2::SubnetRouteTableAssociation", name})
+					}
+				}
+				// CreateHostedZone returns {"hostedZone": {"id": "/hostedzone/Z..."}}
+				if hostedZone, ok := child.(map[string]interface{}); ok && key == "hostedZone" {
+					if id, ok := hostedZone["id"].(string); ok {
+						result = append(result, [2]string{"AWS::Route53::HostedZone", route53HostedZoneId(id)})
+					}
+				}
+				walk(child)
+			}
+		case []interface{}:
+			for _, child := range value {
+				walk(child)
+			}
+		}
+	}
+	walk(record.RequestParameters)
+	walk(record.ResponseElements)
+
+	return result
+}
+
+// trailIdKeyType returns the resource type of the ids of a key of CloudTrail
+// records, ex: AWS::EC2::Instance for instanceId, see
+// resourceHandler.TrailIdKeys.
+func trailIdKeyType(key string) string {
+	for resourceType, handler := range resourceHandlers {
+		if contains(handler.TrailIdKeys, key) {
+			return resourceType
+		}
+	}
+	return ""
+}
+
+// isPrincipal returns true if the event was made by principal: a user name,
+// an ARN, the session name of an assumed role, ex: an instance id, or any
+// session of a role, ex: assumed-role/name. Principals of -lookup match the
+// event like LookupEvents does.
+func isPrincipal(event *trailEvent, principal string) bool {
+	key, value := lookupAttribute(principal)
+	switch key {
+	case "AccessKeyId":
+		return event.AccessKeyId == value
+	case "EventName":
+		return event.EventName == value
+	case "EventSource":
+		return event.EventSource == value
+	case "ResourceName", "ResourceType":
+		for _, resource := range event.Ids {
+			if (key == "ResourceType" && resource[0] == value) ||
+				(key == "ResourceName" && resource[1] == value) {
+				return true
+			}
+		}
+		return false
+	}
+	principal = value
+
+	if strings.HasPrefix(principal, assumedRolePrefix) {
+		return strings.Contains(event.Arn, ":"+principal+"/")
+	}
+	return event.UserName == principal ||
+		event.Arn == principal ||
+		strings.HasSuffix(event.Arn, "/"+principal)
+}
+
+// eventPrincipals returns the principals an event may match, see
+// isPrincipal.
+func eventPrincipals(event *trailEvent) []string {
+	arn := event.Arn
+	principals := []string{}
+	if event.UserName != "" {
+		principals = append(principals, event.UserName)
+	}
+	if arn != "" {
+		principals = append(principals, arn)
+	}
+	for i := 0; i < len(arn); i++ {
+		if arn[i] == '/' {
+			principals = append(principals, arn[i+1:])
+		}
+	}
+	// arn:aws:sts::123:assumed-role/role/session
+	if i := strings.Index(arn, ":"+assumedRolePrefix); i >= 0 {
+		if role := arn[i+1:]; strings.LastIndex(role, "/") > len(assumedRolePrefix) {
+			principals = append(principals, role[:strings.LastIndex(role, "/")])
+		}
+	}
+	return principals
+}
+
+// principalEvents returns the events of the archive made by principal.
+func (archive *trailArchive) principalEvents(principal string) []*trailEvent {
+	candidates := archive.events
+	if key, value := lookupAttribute(principal); key == "Username" {
+		candidates = archive.byPrincipal[value]
+	}
+
+	result := []*trailEvent{}
+	for _, event := range candidates {
+		if isPrincipal(event, principal) {
+			result = append(result, event)
+		}
+	}
+	return result
+}
+
+// isAccountFile returns true if the log file named name may hold the events
+// of account: files of other accounts of an organization trail are skipped.
+func isAccountFile(name string, account string) bool {
+	match := trailAccountRegexp.FindStringSubmatch(filepath.ToSlash(name))
+	return match == nil || match[1] == account
+}
+
+// trailDays returns the days, YYYY/MM/DD, of the log files of the events
+// from start to end, now when zero. Files are delivered a few minutes after
+// their events, the day after end is included. It returns nil, all the days,
+// when start is zero.
+func trailDays(start time.Time, end time.Time) []string {
+	if start.IsZero() {
+		return nil
+	}
+	if end.IsZero() {
+		end = time.Now()
+	}
+	last := end.UTC().AddDate(0, 0, 1).Format("2006/01/02")
+	days := []string{}
+	for day := start.UTC(); day.Format("2006/01/02") <= last; day = day.AddDate(0, 0, 1) {
+		days = append(days, day.Format("2006/01/02"))
+	}
+	return days
+}
+
+// trailPrefixes returns the prefixes to list for the log files of account
+// on days under prefix: the day prefixes of the regions,
+// .../CloudTrail/<region>/YYYY/MM/DD/, found by listing the directories
+// down to the regions. The directories of the other accounts of an
+// organization trail are not listed. prefix is listed whole when it is below
+// a region, or is not laid out like a trail.
+func trailPrefixes(list func(prefix string) ([]string, error), prefix string, account string, days []string) ([]string, error) {
+	if len(days) == 0 ||
+		(strings.Contains("/"+prefix, "/CloudTrail/") && !strings.HasSuffix("/"+prefix, "/CloudTrail/")) {
+		return []string{prefix}, nil
+	}
+	if prefix != "" && !strings.HasSuffix(prefix, "/") {
+		prefix += "/"
+	}
+
+	result := []string{}
+	var descend func(parent string) error
+	descend = func(parent string) error {
+		dirs, err := list(parent)
+		if err != nil {
+			return err
+		}
+		for _, dir := range dirs {
+			name := path.Base(dir)
+			switch {
+			case strings.HasSuffix("/"+parent, "/CloudTrail/"):
+				// dir is a region
+				for _, day := range days {
+					result = append(result, dir+day+"/")
+				}
+			case name == "AWSLogs" || name == "CloudTrail" || name == account || strings.HasPrefix(name, "o-"):
+				if err := descend(dir); err != nil {
+					return err
+				}
+			}
+		}
+		return nil
+	}
+	if err := descend(prefix); err != nil {
+		return nil, err
+	}
+
+	if len(result) == 0 {
+		return []string{prefix}, nil
+	}
+	return result, nil
+}
+
+// trailFiles calls read for each log file of location, a local directory or
+// an s3://bucket/prefix, of account, dated from the day of start to the day
+// after end. Only the directories of these days are listed.
+func trailFiles(location string, account string, start time.Time, end time.Time, read func(name string, r io.Reader) error) error {
+	days := trailDays(start, end)
+	first, last := "0000/00/00", "9999/99/99"
+	if len(days) > 0 {
+		first, last = days[0], days[len(days)-1]
+	}
+	isRecent := func(name string) bool {
+		match := trailDateRegexp.FindStringSubmatch(filepath.ToSlash(name))
+		return (match == nil || (match[1] >= first && match[1] <= last)) && isAccountFile(name, account)
+	}
+	isLog := func(name string) bool {
+		return strings.HasSuffix(name, ".json.gz") || strings.HasSuffix(name, ".json")
+	}
+
+	if !strings.HasPrefix(location, "s3://") {
+		return filepath.Walk(location, func(path string, info os.FileInfo, err error) error {
+			if err != nil {
+				return err
+			}
+			if info.IsDir() {
+				if path == location {
+					return nil
+				}
+				// The years, months and days out of range, and the other
+				// accounts
+				if match := trailDateDirRegexp.FindStringSubmatch(filepath.ToSlash(path)); match != nil &&
+					(match[1] < first[:len(match[1])] || match[1] > last[:len(match[1])]) {
+					return filepath.SkipDir
+				}
+				if !isAccountFile(path+string(filepath.Separator), account) {
+					return filepath.SkipDir
+				}
+				return nil
+			}
+			if !isLog(path) || !isRecent(path) {
+				return nil
+			}
+			file, err := os.Open(path)
+			if err != nil {
+				return err
+			}
+			defer file.Close()
+			return read(path, file)
+		})
+	}
+
+	bucket := strings.TrimPrefix(location, "s3://")
+	prefix := ""
+	if i := strings.Index(bucket, "/"); i >= 0 {
+		bucket, prefix = bucket[:i], bucket[i+1:]
+	}
+	svc, err := s3Client(bucket)
+	if err != nil {
+		return err
+	}
+
+	list := func(prefix string) ([]string, error) {
+		dirs := []string{}
+		err := svc.ListObjectsV2Pages(
+			&s3.ListObjectsV2Input{
+				Bucket:    aws.String(bucket),
+				Prefix:    aws.String(prefix),
+				Delimiter: aws.String("/"),
+			},
+			func(page *s3.ListObjectsV2Output, lastPage bool) bool {
+				for _, common := range page.CommonPrefixes {
+					dirs = append(dirs, aws.StringValue(common.Prefix))
+				}
+				return true
+			})
+		return dirs, err
+	}
+	prefixes, err := trailPrefixes(list, prefix, account, days)
+	if err != nil {
+		return err
+	}
+	v("trailFiles(", location, ") prefixes", len(prefixes))
+
+	for _, prefix := range prefixes {
+		var readErr error
+		err = svc.ListObjectsV2Pages(
+			&s3.ListObjectsV2Input{
+				Bucket: aws.String(bucket),
+				Prefix: aws.String(prefix),
+			},
+			func(page *s3.ListObjectsV2Output, lastPage bool) bool {
+				for _, object := range page.Contents {
+					key := aws.StringValue(object.Key)
+					if !isLog(key) || !isRecent(key) {
+						continue
+					}
+					result, err := svc.GetObject(&s3.GetObjectInput{
+						Bucket: aws.String(bucket),
+						Key:    object.Key,
+					})
+					if err != nil {
+						readErr = err
+						return false
+					}
+					readErr = read(key, result.Body)
+					result.Body.Close()
+					if readErr != nil {
+						return false
+					}
+				}
+				return true
+			})
+		if err == nil {
+			err = readErr
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// decodeTrailRecords calls add for each record of a log file,
+// {"Records": [...]}, decoded one at a time.
+func decodeTrailRecords(r io.Reader, add func(record *trailRecord)) error {
+	decoder := json.NewDecoder(r)
+	if _, err := decoder.Token(); err != nil {
+		return err
+	}
+	for decoder.More() {
+		key, err := decoder.Token()
+		if err != nil {
+			return err
+		}
+		if key != "Records" {
+			var skipped json.RawMessage
+			if err := decoder.Decode(&skipped); err != nil {
+				return err
+			}
+			continue
+		}
+		token, err := decoder.Token()
+		if err != nil {
+			return err
+		}
+		if token != json.Delim('[') {
+			return fmt.Errorf("Records is not an array: %v", token)
+		}
+		for decoder.More() {
+			record := &trailRecord{}
+			if err := decoder.Decode(record); err != nil {
+				return err
+			}
+			add(record)
+		}
+		if _, err := decoder.Token(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// loadTrail reads the events of the account being audited made from start to
+// end, zero for now, in the CloudTrail log files archived in location.
+// Organization trails archive the events of all the accounts, the records of
+// the other accounts are skipped. Only the events referencing resources are
+// kept, without their request and response.
+func loadTrail(location string, start time.Time, end time.Time) error {
+	account := currentAccount
+	if account == "" {
+		var err error
+		if account, err = callerAccount(); err != nil {
+			return err
+		}
+	}
+	v("loadTrail(", location, ",", account, ",", start, ",", end, ")")
+
+	archive := &trailArchive{
+		events:      []*trailEvent{},
+		byPrincipal: map[string][]*trailEvent{},
+	}
+	files := 0
+
+	err := trailFiles(location, account, start, end, func(name string, r io.Reader) error {
+		files++
+		if strings.HasSuffix(name, ".gz") {
+			gz, err := gzip.NewReader(r)
+			if err != nil {
+				return err
+			}
+			defer gz.Close()
+			r = gz
+		}
+
+		err := decodeTrailRecords(r, func(record *trailRecord) {
+			// Like LookupEvents, only the events referencing resources
+			if record.RecipientAccountId != account ||
+				record.EventTime.Before(start) ||
+				(!end.IsZero() && record.EventTime.After(end)) ||
+				record.ErrorCode != "" {
+				return
+			}
+			event := newTrailEvent(record)
+			if len(event.Ids) == 0 {
+				return
+			}
+			archive.events = append(archive.events, event)
+			for _, principal := range eventPrincipals(event) {
+				archive.byPrincipal[principal] = append(archive.byPrincipal[principal], event)
+			}
+		})
+		if err != nil {
+			logErr.Println("Got error reading", name)
+			logErr.Println(err.Error())
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	v("loadTrail(", location, ") files", files, "events", len(archive.events))
+	trail = archive
+	return nil
+}
+
+// searchTrailResources returns the resources introduced by principal in the
+// regions after start, from the archive read by loadTrail.
+// It replaces LookupEvents, which is throttled and limited to 90 days.
+func searchTrailResources(regions []string, principal string, start time.Time) []*Resource {
+	v("searchTrailResources(", principal, ",", start, ")")
+	seen := map[string]*Resource{}
+	resources := []*Resource{}
+
+	for _, event := range trail.principalEvents(principal) {
+		if event.EventTime.Before(start) ||
+			!IsInterestingEvent(event.EventSource, event.EventName, event.ReadOnly) {
+			continue
+		}
+
+		for _, resource := range eventResources(event) {
+			resourceType, resourceName := resource[0], resource[1]
+			resourceRegion := event.AwsRegion
+			if isGlobalType(resourceType) {
+				resourceRegion = globalRegion
+			} else if !contains(regions, resourceRegion) {
+				continue
+			}
+
+			key := resourceKey(resourceType, resourceRegion, resourceName)
+			if found, ok := seen[key]; ok {
+				// Files are not read in order, keep the event that
+				// created the resource.
+				if event.EventTime.Before(found.EventTime) {
+					found.EventName = event.EventName
+					found.EventTime = event.EventTime
+					found.EventID = event.EventID
+				}
+				continue
+			}
+			found := &Resource{
+				Type:      resourceType,
+				Name:      resourceName,
+				Region:    resourceRegion,
+				EventName: event.EventName,
+				EventTime: event.EventTime,
+				EventID:   event.EventID,
+				Principal: principal,
+				Chain:     []string{principal},
+				FoundBy:   []string{backendCloudtrail},
+			}
+			resources = append(resources, found)
+			seen[key] = found
+			v("└──", resourceRegion, resourceType, resourceName)
+		}
+	}
+
+	v("searchTrailResources(", principal, ") resources", len(resources))
+	return resources
+}
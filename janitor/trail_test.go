@@ -0,0 +1,269 @@
+package main
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+)
+
+// testEvent decodes a CloudTrail record into the event the archive keeps.
+func testEvent(t *testing.T, content string) *trailEvent {
+	t.Helper()
+	record := &trailRecord{}
+	if err := json.Unmarshal([]byte(content), record); err != nil {
+		t.Fatal(err)
+	}
+	return newTrailEvent(record)
+}
+
+func TestEventResources(t *testing.T) {
+	tests := []struct {
+		name   string
+		record string
+		want   [][2]string
+	}{
+		{
+			name: "instances and their interfaces, not the network they run in",
+			record: `{"eventName": "RunInstances",
+				"requestParameters": {"subnetId": "subnet-1", "keyName": "key", "groupSet": {"items": [{"groupId": "sg-1"}]}},
+				"responseElements": {"instancesSet": {"items": [{"instanceId": "i-1", "subnetId": "subnet-1", "vpcId": "vpc-1",
+					"networkInterfaceSet": {"items": [{"networkInterfaceId": "eni-1", "groupSet": {"items": [{"groupId": "sg-1"}]}}]}}]}}}`,
+			want: [][2]string{
+				{"AWS::EC2::Instance", "i-1"},
+				{"AWS::EC2::NetworkInterface", "eni-1"},
+			},
+		},
+		{
+			name: "subnet, not its VPC",
+			record: `{"eventName": "CreateSubnet",
+				"requestParameters": {"vpcId": "vpc-1", "cidrBlock": "10.0.0.0/24"},
+				"responseElements": {"subnet": {"subnetId": "subnet-1", "vpcId": "vpc-1"}}}`,
+			want: [][2]string{{"AWS::EC2::Subnet", "subnet-1"}},
+		},
+		{
+			name: "EIP by public ip",
+			record: `{"eventName": "AllocateAddress",
+				"responseElements": {"publicIp": "192.0.2.1", "allocationId": "eipalloc-1"}}`,
+			want: [][2]string{{"AWS::EC2::EIP", "192.0.2.1"}},
+		},
+		{
+			name: "route table association",
+			record: `{"eventName": "AssociateRouteTable",
+				"requestParameters": {"routeTableId": "rtb-1", "subnetId": "subnet-1"},
+				"responseElements": {"associationId": "rtbassoc-1"}}`,
+			want: [][2]string{{"AWS::EC2::SubnetRouteTableAssociation", "rtbassoc-1"}},
+		},
+		{
+			name: "hosted zone",
+			record: `{"eventName": "CreateHostedZone",
+				"requestParameters": {"name": "example.com"},
+				"responseElements": {"hostedZone": {"id": "/hostedzone/Z0123", "name": "example.com."}}}`,
+			want: [][2]string{{"AWS::Route53::HostedZone", "Z0123"}},
+		},
+		{
+			name: "modification",
+			record: `{"eventName": "AuthorizeSecurityGroupIngress",
+				"requestParameters": {"groupId": "sg-1"}}`,
+			want: [][2]string{},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got := eventResources(testEvent(t, test.record))
+			sort.Slice(got, func(i, j int) bool { return got[i][0] < got[j][0] })
+			sort.Slice(test.want, func(i, j int) bool { return test.want[i][0] < test.want[j][0] })
+			if !reflect.DeepEqual(got, test.want) {
+				t.Errorf("eventResources() = %v, want %v", got, test.want)
+			}
+		})
+	}
+}
+
+func TestEventPrincipals(t *testing.T) {
+	tests := []struct {
+		name   string
+		record string
+		want   []string
+	}{
+		{
+			name:   "user",
+			record: `{"userIdentity": {"userName": "alice", "arn": "arn:aws:iam::123456789012:user/alice"}}`,
+			want:   []string{"alice", "arn:aws:iam::123456789012:user/alice", "alice"},
+		},
+		{
+			name:   "role session",
+			record: `{"userIdentity": {"arn": "arn:aws:sts::123456789012:assumed-role/installer/i-0123"}}`,
+			want: []string{
+				"arn:aws:sts::123456789012:assumed-role/installer/i-0123",
+				"installer/i-0123",
+				"i-0123",
+				"assumed-role/installer",
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if got := eventPrincipals(testEvent(t, test.record)); !reflect.DeepEqual(got, test.want) {
+				t.Errorf("eventPrincipals() = %v, want %v", got, test.want)
+			}
+		})
+	}
+}
+
+func TestIsAccountFile(t *testing.T) {
+	tests := []struct {
+		name string
+		want bool
+	}{
+		{"trail/AWSLogs/123456789012/CloudTrail/us-east-1/2019/01/14/file.json.gz", true},
+		{"trail/AWSLogs/210987654321/CloudTrail/us-east-1/2019/01/14/file.json.gz", false},
+		{"trail/AWSLogs/o-abc123/123456789012/CloudTrail/us-east-1/2019/01/14/file.json.gz", true},
+		{"trail/AWSLogs/o-abc123/210987654321/CloudTrail/us-east-1/2019/01/14/file.json.gz", false},
+		{"exported/file.json", true},
+	}
+
+	for _, test := range tests {
+		if got := isAccountFile(test.name, "123456789012"); got != test.want {
+			t.Errorf("isAccountFile(%s) = %v, want %v", test.name, got, test.want)
+		}
+	}
+}
+
+func TestLoadTrail(t *testing.T) {
+	dir := t.TempDir()
+	write := func(name string, content string) {
+		path := filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+			t.Fatal(err)
+		}
+		if err := ioutil.WriteFile(path, []byte(content), 0600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	record := func(account string, user string, eventTime string, instance string, errorCode string) string {
+		return `{"eventName": "RunInstances", "eventTime": "` + eventTime + `", "awsRegion": "us-east-1",
+			"recipientAccountId": "` + account + `", "errorCode": "` + errorCode + `",
+			"userIdentity": {"userName": "` + user + `", "arn": "arn:aws:iam::` + account + `:user/` + user + `"},
+			"responseElements": {"instancesSet": {"items": [{"instanceId": "` + instance + `"}]}}}`
+	}
+
+	write("AWSLogs/o-abc123/123456789012/CloudTrail/us-east-1/2019/01/14/a.json", `{"Records": [`+
+		record("123456789012", "alice", "2019-01-14T08:00:00Z", "i-alice", "")+`,`+
+		record("123456789012", "bob", "2019-01-14T08:00:00Z", "i-bob", "")+`,`+
+		record("123456789012", "alice", "2019-01-14T06:00:00Z", "i-before", "")+`,`+
+		record("123456789012", "alice", "2019-01-14T08:00:00Z", "i-failed", "Client.UnauthorizedOperation")+`,`+
+		// Misplaced record of another account
+		record("210987654321", "alice", "2019-01-14T08:00:00Z", "i-misplaced", "")+`]}`)
+	write("AWSLogs/o-abc123/210987654321/CloudTrail/us-east-1/2019/01/14/b.json", `{"Records": [`+
+		record("210987654321", "alice", "2019-01-14T08:00:00Z", "i-other", "")+`]}`)
+	write("AWSLogs/o-abc123/123456789012/CloudTrail/us-east-1/2019/01/13/c.json", `{"Records": [`+
+		record("123456789012", "alice", "2019-01-13T08:00:00Z", "i-old", "")+`]}`)
+	// Delivered the next day, the records are decoded one by one past the
+	// other keys
+	write("AWSLogs/o-abc123/123456789012/CloudTrail/us-east-1/2019/01/15/d.json", `{"Version": "1.0", "Digest": {"a": [1, 2]}, "Records": [`+
+		record("123456789012", "alice", "2019-01-14T23:58:00Z", "i-late", "")+`,`+
+		record("123456789012", "alice", "2019-01-15T12:00:00Z", "i-after", "")+`]}`)
+	// Not read, dated after the day after the end
+	write("AWSLogs/o-abc123/123456789012/CloudTrail/us-east-1/2019/01/17/e.json", `{"Records": [`+
+		record("123456789012", "alice", "2019-01-14T09:00:00Z", "i-future", "")+`]}`)
+
+	defer func(account string) { currentAccount = account }(currentAccount)
+	currentAccount = "123456789012"
+	start := time.Date(2019, 1, 14, 7, 0, 0, 0, time.UTC)
+	end := time.Date(2019, 1, 15, 0, 0, 0, 0, time.UTC)
+	if err := loadTrail(dir, start, end); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		principal string
+		want      []string
+	}{
+		{"alice", []string{"i-alice", "i-late"}},
+		{"bob", []string{"i-bob"}},
+		{"arn:aws:iam::123456789012:user/bob", []string{"i-bob"}},
+		{"carol", []string{}},
+	}
+	for _, test := range tests {
+		got := []string{}
+		for _, resource := range searchTrailResources([]string{"us-east-1"}, test.principal, start) {
+			got = append(got, resource.Name)
+		}
+		if !reflect.DeepEqual(got, test.want) {
+			t.Errorf("resources of %s = %v, want %v", test.principal, got, test.want)
+		}
+	}
+}
+
+func TestTrailDays(t *testing.T) {
+	start := time.Date(2019, 12, 30, 22, 0, 0, 0, time.FixedZone("EST", -5*3600))
+	end := time.Date(2020, 1, 1, 12, 0, 0, 0, time.UTC)
+	want := []string{"2019/12/31", "2020/01/01", "2020/01/02"}
+	if got := trailDays(start, end); !reflect.DeepEqual(got, want) {
+		t.Errorf("trailDays() = %v, want %v", got, want)
+	}
+	if got := trailDays(time.Time{}, end); got != nil {
+		t.Errorf("trailDays() without start = %v, want all the days", got)
+	}
+}
+
+func TestTrailPrefixes(t *testing.T) {
+	// Directories of an organization trail in s3://bucket/trails
+	dirs := map[string][]string{
+		"trails/":                  {"trails/AWSLogs/"},
+		"trails/AWSLogs/":          {"trails/AWSLogs/o-abc123/"},
+		"trails/AWSLogs/o-abc123/": {"trails/AWSLogs/o-abc123/123456789012/", "trails/AWSLogs/o-abc123/210987654321/"},
+		"trails/AWSLogs/o-abc123/123456789012/": {
+			"trails/AWSLogs/o-abc123/123456789012/CloudTrail/",
+			"trails/AWSLogs/o-abc123/123456789012/CloudTrail-Digest/",
+		},
+		"trails/AWSLogs/o-abc123/123456789012/CloudTrail/": {
+			"trails/AWSLogs/o-abc123/123456789012/CloudTrail/eu-west-1/",
+			"trails/AWSLogs/o-abc123/123456789012/CloudTrail/us-east-1/",
+		},
+	}
+	listed := []string{}
+	list := func(prefix string) ([]string, error) {
+		listed = append(listed, prefix)
+		return dirs[prefix], nil
+	}
+	days := []string{"2019/01/14", "2019/01/15"}
+
+	got, err := trailPrefixes(list, "trails", "123456789012", days)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{
+		"trails/AWSLogs/o-abc123/123456789012/CloudTrail/eu-west-1/2019/01/14/",
+		"trails/AWSLogs/o-abc123/123456789012/CloudTrail/eu-west-1/2019/01/15/",
+		"trails/AWSLogs/o-abc123/123456789012/CloudTrail/us-east-1/2019/01/14/",
+		"trails/AWSLogs/o-abc123/123456789012/CloudTrail/us-east-1/2019/01/15/",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("trailPrefixes() = %v, want %v", got, want)
+	}
+	for _, prefix := range listed {
+		if strings.Contains(prefix, "210987654321") || strings.Contains(prefix, "Digest") {
+			t.Errorf("listed %s", prefix)
+		}
+	}
+
+	// Below a region, or not a trail, the prefix is listed whole
+	for _, prefix := range []string{"trails/AWSLogs/o-abc123/123456789012/CloudTrail/us-east-1/2019/", "exported/"} {
+		got, err := trailPrefixes(list, prefix, "123456789012", days)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(got, []string{prefix}) {
+			t.Errorf("trailPrefixes(%s) = %v", prefix, got)
+		}
+	}
+}
@@ -0,0 +1,110 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// userStart is a user to search, from -u and -t or from a line of the
+// -users file.
+type userStart struct {
+	User      string    `json:"user" yaml:"user"`
+	StartTime time.Time `json:"start_time" yaml:"start_time"`
+	// Principals are the user and the -lookup attributes to search
+	Principals []string `json:"-" yaml:"-"`
+}
+
+// readUsers reads user,start-time lines. Empty lines and lines starting with
+// # are skipped.
+func readUsers(r io.Reader) ([]*userStart, error) {
+	users := []*userStart{}
+	scanner := bufio.NewScanner(r)
+	line := 0
+	for scanner.Scan() {
+		line++
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" || strings.HasPrefix(text, "#") {
+			continue
+		}
+
+		fields := strings.Split(text, ",")
+		if len(fields) != 2 {
+			return nil, fmt.Errorf("line %d: expected user,start-time", line)
+		}
+		user := strings.TrimSpace(fields[0])
+		start, err := time.Parse(time.RFC3339, strings.TrimSpace(fields[1]))
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %s", line, err)
+		}
+		users = append(users, &userStart{
+			User:       user,
+			StartTime:  start,
+			Principals: []string{user},
+		})
+	}
+	return users, scanner.Err()
+}
+
+// loadUsers reads the -users file, - for stdin.
+func loadUsers(path string) ([]*userStart, error) {
+	if path == "-" {
+		return readUsers(os.Stdin)
+	}
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	return readUsers(file)
+}
+
+// searchUser returns the resources found in CloudTrail for a user, and with
+// -r for the principals it spawned.
+func searchUser(regions []string, user *userStart) []*Resource {
+	resources := []*Resource{}
+	for _, principal := range user.Principals {
+		resources = mergeResources(resources, searchCloudtrail(regions, principal, user.StartTime))
+	}
+	if recursive {
+		resources = searchRecursive(regions, resources, depth, user.Principals, user.StartTime)
+	}
+
+	for _, resource := range resources {
+		if usersFile != "" {
+			resource.Users = []string{user.User}
+		}
+		resource.startTime = user.StartTime
+	}
+	return resources
+}
+
+// searchUsers searches the users concurrently, the calls share the rate
+// limiters of the session. Resources found by several users are reported
+// once, with all their users, the earliest start time applies.
+func searchUsers(regions []string, users []*userStart) []*Resource {
+	found := make([][]*Resource, len(users))
+	slots := make(chan bool, concurrency)
+	var wg sync.WaitGroup
+
+	for i, user := range users {
+		wg.Add(1)
+		go func(i int, user *userStart) {
+			defer wg.Done()
+			slots <- true
+			defer func() { <-slots }()
+			found[i] = searchUser(regions, user)
+		}(i, user)
+	}
+	wg.Wait()
+
+	resources := []*Resource{}
+	for _, userResources := range found {
+		resources = mergeResources(resources, userResources)
+	}
+	return resources
+}
@@ -0,0 +1,37 @@
+package main
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestReadUsers(t *testing.T) {
+	users, err := readUsers(strings.NewReader(`# CI jobs of 2019-01-14
+alice,2019-01-14T07:04:25Z
+
+ ci-op-abcd , 2019-01-14T09:00:00+01:00
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(users) != 2 {
+		t.Fatalf("%d users, want 2", len(users))
+	}
+	if users[1].User != "ci-op-abcd" || !users[1].StartTime.Equal(time.Date(2019, 1, 14, 8, 0, 0, 0, time.UTC)) {
+		t.Errorf("second user %+v", users[1])
+	}
+	if len(users[0].Principals) != 1 || users[0].Principals[0] != "alice" {
+		t.Errorf("alice searches %v", users[0].Principals)
+	}
+
+	for _, content := range []string{
+		"alice\n",
+		"alice,2019-01-14T07:04:25Z,extra\n",
+		"alice,2019-01-14\n",
+	} {
+		if _, err := readUsers(strings.NewReader(content)); err == nil || !strings.HasPrefix(err.Error(), "line 1:") {
+			t.Errorf("%q: got %v, want an error on line 1", content, err)
+		}
+	}
+}